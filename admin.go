@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+)
+
+// setAdmin grants or revokes the admin rights of the user named by the first argument
+func setAdmin(s *state, cmd command, isAdmin bool) (database.User, error) {
+	target, err := s.db.GetUser(context.Background(), cmd.args[0])
+	if err != nil {
+		return database.User{}, fmt.Errorf("error: user '%v' not registered", cmd.args[0])
+	}
+	err = s.db.SetUserAdmin(
+		context.Background(),
+		database.SetUserAdminParams{
+			ID:        target.ID,
+			IsAdmin:   isAdmin,
+			UpdatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return database.User{}, fmt.Errorf("error: could not update admin rights of %v \n%v", target.Name, err)
+	}
+	return target, nil
+}
+
+func handlerAdminGrant(s *state, cmd command, user database.User) error {
+	target, err := setAdmin(s, cmd, true)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "%v is now an admin\n", target.Name)
+	return nil
+}
+
+func handlerAdminRevoke(s *state, cmd command, user database.User) error {
+	admins, err := s.db.GetAdmins(context.Background())
+	if err != nil {
+		return fmt.Errorf("error: could not retreive admins from db \n%v", err)
+	}
+	if len(admins) == 1 && admins[0] == cmd.args[0] {
+		return fmt.Errorf("error: %v is the only admin, make someone else an admin first", cmd.args[0])
+	}
+	target, err := setAdmin(s, cmd, false)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "%v is no longer an admin\n", target.Name)
+	return nil
+}
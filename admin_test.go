@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestAdmin(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	ctx := context.Background()
+	mustRun(t, s, "login", "bob")
+	if err := run(t, s, "reset", "--yes"); err == nil || !strings.Contains(err.Error(), "only admins") {
+		t.Errorf("got %v, want reset to be refused to non-admins", err)
+	}
+	if err := run(t, s, "admin", "grant", "bob"); err == nil {
+		t.Error("non-admins could grant admin rights")
+	}
+
+	// An admin without a password has no session, so logging in as them gives no admin rights
+	mustRun(t, s, "login", "alice")
+	for _, args := range [][]string{{"admin", "grant", "bob"}, {"prune"}, {"reset", "--yes"}} {
+		if err := run(t, s, args[0], args[1:]...); err == nil || !strings.Contains(err.Error(), "admins need a password") {
+			t.Errorf("%v: got %v, want a passwordless admin to be refused", args, err)
+		}
+	}
+	alice, _ := s.db.GetUser(ctx, "alice")
+	if canManageFeed(alice, database.Feed{UserID: uuid.New()}) {
+		t.Error("a passwordless admin could manage every feed")
+	}
+
+	loginWithPassword(t, s, "alice")
+	if err := run(t, s, "admin", "revoke", "alice"); err == nil || !strings.Contains(err.Error(), "only admin") {
+		t.Errorf("got %v, want revoking the last admin to fail", err)
+	}
+	mustRun(t, s, "admin", "grant", "bob")
+	loginWithPassword(t, s, "bob")
+	if bob, _ := s.db.GetUser(ctx, "bob"); !bob.IsAdmin || !canManageFeed(bob, database.Feed{UserID: uuid.New()}) {
+		t.Error("admins should manage every feed")
+	}
+	mustRun(t, s, "admin", "revoke", "bob")
+	loginWithPassword(t, s, "alice")
+
+	for _, in := range []string{"", "no\n"} {
+		s.in = strings.NewReader(in)
+		if err := run(t, s, "reset"); err == nil || !strings.Contains(err.Error(), "aborted") {
+			t.Errorf("%q: got %v, want reset to be aborted", in, err)
+		}
+	}
+	if users, _ := s.db.GetUsers(ctx); len(users) != 2 {
+		t.Fatalf("aborted reset deleted users: %v", users)
+	}
+	if err := run(t, s, "reset", "--posts-only", "--user", "bob"); exitCode(err) != exitUsage {
+		t.Errorf("got %v, want a usage error", err)
+	}
+
+	mustRun(t, s, "reset", "--posts-only", "--yes")
+	if n, _ := s.db.CountPostsForFeed(ctx, uuid.MustParse("00000000-0000-0000-0000-0000000000f1")); n != 0 {
+		t.Errorf("%v posts left", n)
+	}
+	if users, _ := s.db.GetUsers(ctx); len(users) != 2 {
+		t.Errorf("--posts-only deleted users: %v", users)
+	}
+
+	if err := run(t, s, "reset", "--user", "alice", "--yes"); err == nil {
+		t.Error("deleted the only admin")
+	}
+	mustRun(t, s, "reset", "--user", "bob", "--yes")
+	if users, _ := s.db.GetUsers(ctx); fmt.Sprint(users) != "[alice]" {
+		t.Errorf("got users %v, want only alice", users)
+	}
+
+	s.in = strings.NewReader("yes\n")
+	mustRun(t, s, "reset")
+	if users, _ := s.db.GetUsers(ctx); len(users) != 0 || s.cfg.CurrentUsername != "" {
+		t.Errorf("got users %v and current user %q after reset", users, s.cfg.CurrentUsername)
+	}
+	mustRun(t, s, "register", "zoe")
+	if zoe, _ := s.db.GetUser(ctx, "zoe"); !zoe.IsAdmin {
+		t.Error("the first user to register should be an admin")
+	}
+	mustRun(t, s, "register", "yan")
+	if yan, _ := s.db.GetUser(ctx, "yan"); yan.IsAdmin {
+		t.Error("only the first user should be an admin")
+	}
+}
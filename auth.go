@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+// sessionDuration is how long a login with a password lasts
+const sessionDuration = 30 * 24 * time.Hour
+
+// readPassword asks for a password, without echoing it when reading from a terminal.
+// It returns "" once input has run out.
+func readPassword(s *state, question string) (string, error) {
+	fmt.Fprintf(s.out, "%v: ", question)
+	if f, ok := s.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(s.out)
+		if err != nil {
+			return "", fmt.Errorf("error: could not read password \n%v", err)
+		}
+		return string(password), nil
+	}
+	line, err := s.readLine()
+	if err == io.EOF {
+		fmt.Fprintln(s.out)
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error: could not read password \n%v", err)
+	}
+	return line, nil
+}
+
+// askNewPassword asks for a password twice and returns its hash, or NULL when none is given
+func askNewPassword(s *state, question string) (sql.NullString, error) {
+	password, err := readPassword(s, question)
+	if err != nil || password == "" {
+		return sql.NullString{}, err
+	}
+	repeated, err := readPassword(s, "Repeat password")
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if repeated != password {
+		return sql.NullString{}, fmt.Errorf("error: passwords do not match")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("error: could not hash password \n%v", err)
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+// checkPassword asks for the password of user, if they have one
+func checkPassword(s *state, user database.User) error {
+	if !user.PasswordHash.Valid {
+		return nil
+	}
+	password, err := readPassword(s, fmt.Sprintf("Password for %v", user.Name))
+	if err != nil {
+		return err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+	if err != nil {
+		return fmt.Errorf("error: wrong password for %v", user.Name)
+	}
+	return nil
+}
+
+// hashToken returns what is stored of a session token, so that the tokens
+// themselves never reach the database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// startSession logs user in, ending the session of the previous user.
+// Users with a password get a session token, saved to the config file, that expires after sessionDuration.
+func startSession(s *state, user database.User) error {
+	err := endSession(s)
+	if err != nil {
+		return err
+	}
+	token := ""
+	if user.PasswordHash.Valid {
+		token = rand.Text()
+		_, err = s.db.CreateSession(
+			context.Background(),
+			database.CreateSessionParams{
+				TokenHash: hashToken(token),
+				UserID:    user.ID,
+				CreatedAt: time.Now(),
+				ExpiresAt: time.Now().Add(sessionDuration),
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("error: could not start session \n%v", err)
+		}
+	}
+	return s.cfg.SetUser(user.Name, token)
+}
+
+// endSession deletes the session saved in the config file, along with any that have expired
+func endSession(s *state) error {
+	if s.cfg.SessionToken != "" {
+		err := s.db.DeleteSession(context.Background(), hashToken(s.cfg.SessionToken))
+		if err != nil {
+			return fmt.Errorf("error: could not end session \n%v", err)
+		}
+	}
+	_, err := s.db.DeleteExpiredSessions(context.Background(), time.Now())
+	if err != nil {
+		return fmt.Errorf("error: could not delete expired sessions \n%v", err)
+	}
+	return nil
+}
+
+// checkSession makes sure the config file holds a live session of user, if they have a password
+func checkSession(s *state, user database.User) error {
+	if !user.PasswordHash.Valid {
+		return nil
+	}
+	if s.cfg.SessionToken == "" {
+		return fmt.Errorf("error: %v has a password, log in with 'gator login %v'", user.Name, user.Name)
+	}
+	session, err := s.db.GetSession(context.Background(), hashToken(s.cfg.SessionToken))
+	if err != nil || session.UserID != user.ID {
+		return fmt.Errorf("error: %v has a password, log in with 'gator login %v'", user.Name, user.Name)
+	}
+	if !session.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("error: session of %v expired, log in again with 'gator login %v'", user.Name, user.Name)
+	}
+	return nil
+}
+
+func handlerLogout(s *state, cmd command) error {
+	err := endSession(s)
+	if err != nil {
+		return err
+	}
+	username := s.cfg.CurrentUsername
+	err = s.cfg.SetUser("", "")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Logged out %v\n", username)
+	return nil
+}
+
+// handlerPasswd sets, changes or removes the password of the current user.
+// Every session of the user ends, and this one starts over.
+func handlerPasswd(s *state, cmd command, user database.User) error {
+	hash := sql.NullString{}
+	if !cmd.has("remove") {
+		var err error
+		hash, err = askNewPassword(s, "New password")
+		if err != nil {
+			return err
+		}
+		if !hash.Valid {
+			return fmt.Errorf("error: no password given, use --remove to remove it")
+		}
+	}
+	err := s.db.SetUserPassword(
+		context.Background(),
+		database.SetUserPasswordParams{
+			ID:           user.ID,
+			PasswordHash: hash,
+			UpdatedAt:    time.Now(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not update password \n%v", err)
+	}
+	_, err = s.db.DeleteSessionsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not end sessions of %v \n%v", user.Name, err)
+	}
+	user.PasswordHash = hash
+	s.cfg.SessionToken = ""
+	err = startSession(s, user)
+	if err != nil {
+		return err
+	}
+	if hash.Valid {
+		fmt.Fprintf(s.out, "Password of %v set, other sessions were logged out\n", user.Name)
+	} else {
+		fmt.Fprintf(s.out, "Password of %v removed\n", user.Name)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasswords(t *testing.T) {
+	s := newTestState(t)
+	ctx := context.Background()
+	s.in = strings.NewReader("secret\nsecret\n")
+	mustRun(t, s, "register", "alice")
+	token := s.cfg.SessionToken
+	if token == "" {
+		t.Fatal("registering with a password should start a session")
+	}
+	alice, _ := s.db.GetUser(ctx, "alice")
+	if session, err := s.db.GetSession(ctx, hashToken(token)); err != nil || session.UserID != alice.ID {
+		t.Fatalf("got session %+v %v, want one of alice", session, err)
+	}
+	mustRun(t, s, "following")
+
+	s.in = strings.NewReader("one\ntwo\n")
+	if err := run(t, s, "register", "bob"); err == nil || !strings.Contains(err.Error(), "do not match") {
+		t.Errorf("got %v, want mismatching passwords to fail", err)
+	}
+	if userExists(s, "bob") {
+		t.Error("bob was registered despite mismatching passwords")
+	}
+	s.in = strings.NewReader("\n")
+	mustRun(t, s, "register", "bob")
+	if s.cfg.SessionToken != "" {
+		t.Error("users without a password need no session")
+	}
+	if _, err := s.db.GetSession(ctx, hashToken(token)); err == nil {
+		t.Error("logging in as someone else should end the previous session")
+	}
+
+	// Switching to alice by editing the config is not enough
+	s.cfg.CurrentUsername = "alice"
+	if err := run(t, s, "following"); err == nil || !strings.Contains(err.Error(), "log in with 'gator login alice'") {
+		t.Errorf("got %v, want a missing session error", err)
+	}
+	s.in = strings.NewReader("wrong\n")
+	if err := run(t, s, "login", "alice"); err == nil || !strings.Contains(err.Error(), "wrong password") {
+		t.Errorf("got %v, want a wrong password error", err)
+	}
+	s.in = strings.NewReader("secret\n")
+	mustRun(t, s, "login", "alice")
+	mustRun(t, s, "following")
+
+	s.db.DeleteExpiredSessions(ctx, time.Now().Add(sessionDuration+time.Minute))
+	if err := run(t, s, "following"); err == nil {
+		t.Error("an expired session should need logging in again")
+	}
+	s.in = strings.NewReader("secret\n")
+	mustRun(t, s, "login", "alice")
+
+	s.in = strings.NewReader("better\nbetter\n")
+	mustRun(t, s, "passwd")
+	mustRun(t, s, "following")
+	s.in = strings.NewReader("secret\n")
+	if err := run(t, s, "login", "alice"); err == nil {
+		t.Error("the old password still works")
+	}
+	mustRun(t, s, "passwd", "--remove")
+	if s.cfg.SessionToken != "" {
+		t.Error("a session was kept after removing the password")
+	}
+	mustRun(t, s, "login", "alice")
+
+	mustRun(t, s, "logout")
+	if s.cfg.CurrentUsername != "" || run(t, s, "following") == nil {
+		t.Errorf("still logged in as %q", s.cfg.CurrentUsername)
+	}
+}
@@ -1,50 +1,790 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
 )
 
 type command struct {
-	name string
-	args []string
+	name  string
+	args  []string
+	flags map[string]string
+}
+
+// flag returns the value given for a flag, empty if it was not given
+func (c command) flag(name string) string {
+	return c.flags[name]
+}
+
+// has reports whether a flag was given
+func (c command) has(name string) bool {
+	_, ok := c.flags[name]
+	return ok
+}
+
+// Exit codes, so scripts can tell a failed command from a mistyped one
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// usageError reports a command invoked with the wrong arguments
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usageErrorf(format string, a ...any) error {
+	return usageError{msg: fmt.Sprintf(format, a...)}
+}
+
+// exitCode returns the status gator exits with after err
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var usage usageError
+	if errors.As(err, &usage) {
+		return exitUsage
+	}
+	return exitError
+}
+
+// argDef describes a positional argument of a command
+type argDef struct {
+	name     string
+	optional bool
+	// variadic arguments take every remaining argument
+	variadic bool
+	complete completer
+}
+
+// flagDef describes a flag of a command
+type flagDef struct {
+	name  string
+	short string
+	// value names the flag's value in help, empty for flags that take none
+	value    string
+	usage    string
+	complete completer
+}
+
+func (f flagDef) String() string {
+	str := "--" + f.name
+	if f.short != "" {
+		str += ", -" + f.short
+	}
+	if f.value != "" {
+		str += " " + f.value
+	}
+	return str
+}
+
+// helpFlag is accepted by every command
+var helpFlag = flagDef{name: "help", short: "h", usage: "Show help for the command"}
+
+// commandDef declares a command: the registry derives parsing, validation and help from it.
+// A command with subcommands has no handler of its own, its first argument picks the subcommand.
+type commandDef struct {
+	name        string
+	summary     string
+	args        []argDef
+	flags       []flagDef
+	subcommands []*commandDef
+	handler     func(*state, command) error
+	// skipSchema lets the command and its subcommands run before the database schema is up to date
+	skipSchema bool
+	// hidden commands are left out of help and completion
+	hidden bool
+	parent *commandDef
+}
+
+// path returns the words naming the command, e.g. 'feed rm'
+func (d *commandDef) path() string {
+	if d.parent == nil {
+		return d.name
+	}
+	return d.parent.path() + " " + d.name
+}
+
+func (d *commandDef) usage() string {
+	parts := []string{"gator", d.path()}
+	if len(d.subcommands) > 0 {
+		names := make([]string, len(d.subcommands))
+		for i, sub := range d.subcommands {
+			names[i] = sub.name
+		}
+		parts = append(parts, strings.Join(names, "|"))
+	}
+	for _, arg := range d.args {
+		str := "<" + arg.name + ">"
+		if arg.variadic {
+			str += "..."
+		}
+		if arg.optional {
+			str = "[" + str + "]"
+		}
+		parts = append(parts, str)
+	}
+	for _, f := range d.flags {
+		str := "--" + f.name
+		if f.value != "" {
+			str += " " + f.value
+		}
+		parts = append(parts, "["+str+"]")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (d *commandDef) subcommand(name string) *commandDef {
+	for _, sub := range d.subcommands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// arg returns the definition of the i-th positional argument
+func (d *commandDef) arg(i int) (argDef, bool) {
+	if i < len(d.args) {
+		return d.args[i], true
+	}
+	if len(d.args) > 0 && d.args[len(d.args)-1].variadic {
+		return d.args[len(d.args)-1], true
+	}
+	return argDef{}, false
+}
+
+// allFlags returns the command's flags followed by helpFlag
+func (d *commandDef) allFlags() []flagDef {
+	return append(append([]flagDef{}, d.flags...), helpFlag)
+}
+
+func (d *commandDef) flagDef(name string) (flagDef, bool) {
+	for _, f := range d.allFlags() {
+		if f.name == name || (f.short != "" && f.short == name) {
+			return f, true
+		}
+	}
+	return flagDef{}, false
+}
+
+// parse splits args into positional arguments and flags, and checks both against the definition
+func (d *commandDef) parse(args []string) (command, error) {
+	cmd := command{name: d.path(), args: []string{}, flags: map[string]string{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			cmd.args = append(cmd.args, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			cmd.args = append(cmd.args, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f, ok := d.flagDef(name)
+		if !ok {
+			return cmd, usageErrorf("error: unknown flag '%v' \nusage: %v", arg, d.usage())
+		}
+		switch {
+		case f.value == "" && hasValue:
+			return cmd, usageErrorf("error: flag '--%v' takes no value \nusage: %v", f.name, d.usage())
+		case f.value != "" && !hasValue:
+			if i+1 == len(args) {
+				return cmd, usageErrorf("error: flag '--%v' needs a value \nusage: %v", f.name, d.usage())
+			}
+			i++
+			value = args[i]
+		}
+		cmd.flags[f.name] = value
+	}
+	if cmd.has(helpFlag.name) {
+		return cmd, nil
+	}
+	min, max := 0, 0
+	for _, arg := range d.args {
+		if !arg.optional {
+			min++
+		}
+		if arg.variadic {
+			max = -1
+		} else if max >= 0 {
+			max++
+		}
+	}
+	if len(cmd.args) < min || (max >= 0 && len(cmd.args) > max) {
+		return cmd, usageErrorf("usage: %v", d.usage())
+	}
+	return cmd, nil
 }
 
 type commands struct {
-	registry map[string]func(*state, command) error
+	defs []*commandDef
 }
 
 func newCommands() *commands {
-	c := commands{
-		registry: make(map[string]func(*state, command) error),
+	c := &commands{}
+	c.register(&commandDef{
+		name:       "init",
+		summary:    "Set up the config file and database, asking for anything --db-url and --user do not give",
+		skipSchema: true,
+		handler:    handlerInit,
+	})
+	c.register(&commandDef{
+		name:    "register",
+		summary: "Register a new user",
+		args:    []argDef{{name: "username"}},
+		handler: handlerRegister,
+	})
+	c.register(&commandDef{
+		name:    "login",
+		summary: "Log in as an existing user, asking for their password if they have one",
+		args:    []argDef{{name: "username", complete: completeUsernames}},
+		handler: handlerLogin,
+	})
+	c.register(&commandDef{
+		name:    "logout",
+		summary: "Log out, ending the session of a user with a password",
+		handler: handlerLogout,
+	})
+	c.register(&commandDef{
+		name:    "passwd",
+		summary: "Set or change your password, logging out your other sessions",
+		flags:   []flagDef{{name: "remove", usage: "Remove the password instead"}},
+		handler: middlewareLoggedIn(handlerPasswd),
+	})
+	c.register(&commandDef{
+		name:    "addfeed",
+		summary: "Add a new feed, named after its title unless a name is given",
+		args:    []argDef{{name: "name", optional: true}, {name: "url"}},
+		flags:   []flagDef{{name: "seed", usage: "Save the feed's current posts right away"}},
+		handler: middlewareLoggedIn(handlerAddFeed),
+	})
+	c.register(&commandDef{
+		name:    "feed",
+		summary: "Manage a feed you added",
+		subcommands: []*commandDef{
+			{
+				name:    "rm",
+				summary: "Delete a feed you added, with its follows and posts",
+				args:    []argDef{{name: "url", complete: completeFeedURLs}},
+				handler: middlewareLoggedIn(handlerFeedRemove),
+			},
+			{
+				name:    "rename",
+				summary: "Rename a feed you added",
+				args:    []argDef{{name: "url", complete: completeFeedURLs}, {name: "name", complete: completeFeedName}},
+				handler: middlewareLoggedIn(handlerFeedRename),
+			},
+			{
+				name:    "set-url",
+				summary: "Point a feed you added at a new url",
+				args:    []argDef{{name: "old url", complete: completeFeedURLs}, {name: "new url"}},
+				handler: middlewareLoggedIn(handlerFeedSetURL),
+			},
+			{
+				name:    "history",
+				summary: "Show renames and url changes of a feed",
+				args:    []argDef{{name: "url", complete: completeFeedURLs}},
+				handler: handlerFeedHistory,
+			},
+			{
+				name:    "retention",
+				summary: "Show how long a feed's posts are kept, or set it for a feed you added (0 keeps every post)",
+				args:    []argDef{{name: "url", complete: completeFeedURLs}},
+				flags: []flagDef{
+					{name: "days", value: "n", usage: "Keep posts published in the last n days"},
+					{name: "posts", value: "n", usage: "Keep the newest n posts"},
+					{name: "clear", usage: "Go back to the keep_days and keep_posts of the config"},
+				},
+				handler: middlewareLoggedIn(handlerFeedRetention),
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:    "follow",
+		summary: "Follow an existing feed",
+		args:    []argDef{{name: "url", complete: completeFeedURLs}},
+		flags:   []flagDef{{name: "title", value: "title", usage: "Your own title for the feed, shown to you instead of its name"}},
+		handler: middlewareLoggedIn(handlerFollow),
+	})
+	c.register(&commandDef{
+		name:    "title",
+		summary: "Give a feed you follow your own title, or show it by its name again when none is given",
+		args: []argDef{
+			{name: "url", complete: completeFollowedURLs},
+			{name: "title", optional: true},
+		},
+		handler: middlewareLoggedIn(handlerTitle),
+	})
+	c.register(&commandDef{
+		name:    "unfollow",
+		summary: "Unfollow a feed",
+		args:    []argDef{{name: "url", complete: completeFollowedURLs}},
+		handler: middlewareLoggedIn(handlerUnfollow),
+	})
+	c.register(&commandDef{
+		name:    "browse",
+		summary: "Browse posts from the feeds you follow, newest first (defaults to 2 posts)",
+		args:    []argDef{{name: "limit", optional: true}},
+		flags: []flagDef{
+			{name: "folder", value: "name", usage: "Only show posts from the feeds in this folder", complete: completeFolders},
+			{name: "tag", value: "tag", usage: "Only show posts your rules tagged with tag", complete: completeTags},
+			{name: "hidden", usage: "Show the posts you hid instead"},
+		},
+		handler: middlewareLoggedIn(handlerBrowse),
+	})
+	c.register(&commandDef{
+		name:    "folder",
+		summary: "Sort the feeds you follow into folders",
+		subcommands: []*commandDef{
+			{
+				name:    "add",
+				summary: "Create a folder",
+				args:    []argDef{{name: "name"}},
+				handler: middlewareLoggedIn(handlerFolderAdd),
+			},
+			{
+				name:    "rename",
+				summary: "Rename a folder",
+				args:    []argDef{{name: "name", complete: completeFolders}, {name: "new name"}},
+				handler: middlewareLoggedIn(handlerFolderRename),
+			},
+			{
+				name:    "rm",
+				summary: "Delete a folder, keeping the feeds in it followed",
+				args:    []argDef{{name: "name", complete: completeFolders}},
+				handler: middlewareLoggedIn(handlerFolderRemove),
+			},
+			{
+				name:    "move",
+				summary: "Move a feed you follow to a folder, or out of its folder when none is given",
+				args: []argDef{
+					{name: "url", complete: completeFollowedURLs},
+					{name: "folder", optional: true, complete: completeFolders},
+				},
+				handler: middlewareLoggedIn(handlerFolderMove),
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:    "post",
+		summary: "Mark a post read, starred or hidden",
+		subcommands: []*commandDef{
+			{
+				name:    "read",
+				summary: "Mark a post read",
+				args:    []argDef{{name: "post id", complete: completePostIDs}},
+				handler: middlewareLoggedIn(handlerPostState(setPostRead, true, "Marked read")),
+			},
+			{
+				name:    "unread",
+				summary: "Mark a post unread",
+				args:    []argDef{{name: "post id", complete: completePostIDs}},
+				handler: middlewareLoggedIn(handlerPostState(setPostRead, false, "Marked unread")),
+			},
+			{
+				name:    "star",
+				summary: "Star a post",
+				args:    []argDef{{name: "post id", complete: completePostIDs}},
+				handler: middlewareLoggedIn(handlerPostState(setPostStarred, true, "Starred")),
+			},
+			{
+				name:    "unstar",
+				summary: "Remove the star from a post",
+				args:    []argDef{{name: "post id", complete: completePostIDs}},
+				handler: middlewareLoggedIn(handlerPostState(setPostStarred, false, "Unstarred")),
+			},
+			{
+				name:    "hide",
+				summary: "Hide a post from browse and tui",
+				args:    []argDef{{name: "post id", complete: completePostIDs}},
+				handler: middlewareLoggedIn(handlerPostState(setPostHidden, true, "Hid")),
+			},
+			{
+				name:    "unhide",
+				summary: "Show a hidden post again",
+				args:    []argDef{{name: "post id", complete: completeHiddenPostIDs}},
+				handler: middlewareLoggedIn(handlerPostState(setPostHidden, false, "Unhid")),
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:    "rules",
+		summary: "Hide, mark read, star or tag posts matching your rules as they are saved",
+		subcommands: []*commandDef{
+			{
+				name:    "add",
+				summary: "Add a rule doing action to the posts whose field contains pattern, ignoring case",
+				args: []argDef{
+					{name: "field", complete: choices(ruleFields...)},
+					{name: "pattern"},
+					{name: "action", complete: choices(ruleActions...)},
+					{name: "tag", optional: true, complete: completeTags},
+				},
+				flags: []flagDef{
+					{name: "regex", usage: "Match pattern as a regular expression instead"},
+					{name: "feed", value: "url", usage: "Only apply the rule to this feed", complete: completeFollowedURLs},
+				},
+				handler: middlewareLoggedIn(handlerRulesAdd),
+			},
+			{
+				name:    "list",
+				summary: "List your rules",
+				handler: middlewareLoggedIn(handlerRulesList),
+			},
+			{
+				name:    "rm",
+				summary: "Delete a rule, leaving the posts it matched as they are",
+				args:    []argDef{{name: "rule id", complete: completeRuleIDs}},
+				handler: middlewareLoggedIn(handlerRulesRemove),
+			},
+			{
+				name:    "apply",
+				summary: "Apply your rules to the posts already saved from the feeds you follow",
+				handler: middlewareLoggedIn(handlerRulesApply),
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:    "tui",
+		summary: "Read your feeds in a full-screen terminal interface",
+		handler: middlewareLoggedIn(handlerTUI),
+	})
+	c.register(&commandDef{
+		name:    "shell",
+		summary: "Run commands one after another on one connection, switching users with login",
+		handler: c.handlerShell,
+	})
+	c.register(&commandDef{
+		name:    "feeds",
+		summary: "List all feeds",
+		handler: handlerFeeds,
+	})
+	c.register(&commandDef{
+		name:    "following",
+		summary: "List feeds you are following",
+		handler: middlewareLoggedIn(handlerFollowing),
+	})
+	c.register(&commandDef{
+		name:    "export",
+		summary: "Export feeds",
+		subcommands: []*commandDef{
+			{
+				name:    "opml",
+				summary: "Export followed feeds (or all feeds) as OPML, to stdout or a file",
+				args:    []argDef{{name: "file", optional: true}},
+				flags:   []flagDef{{name: "all", usage: "Export every feed in the database"}},
+				handler: middlewareLoggedIn(handlerExport),
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:    "agg",
+		summary: "Fetch feeds every refresh rate, e.g. '1m' or '1h'",
+		args:    []argDef{{name: "refresh rate"}},
+		flags: []flagDef{
+			{name: "prune-every", value: "interval", usage: "Also prune posts this often, e.g. '24h' (admins only)"},
+		},
+		handler: middlewareLoggedIn(handlerAgg),
+	})
+	c.register(&commandDef{
+		name:    "prune",
+		summary: "Delete posts past their feed's retention, keeping starred and recently saved unread posts (admins only)",
+		flags:   []flagDef{{name: "dry-run", usage: "List the posts that would be deleted without deleting them"}},
+		handler: middlewareAdmin(handlerPrune),
+	})
+	c.register(&commandDef{
+		name:    "users",
+		summary: "List all users",
+		handler: handlerUsers,
+	})
+	c.register(&commandDef{
+		name:    "user",
+		summary: "Manage your account, or anyone's as an admin",
+		subcommands: []*commandDef{
+			{
+				name:    "rename",
+				summary: "Rename your account, or a user's as an admin",
+				args: []argDef{
+					{name: "username", optional: true, complete: completeUsernames},
+					{name: "new name"},
+				},
+				handler: middlewareLoggedIn(handlerUserRename),
+			},
+			{
+				name:    "delete",
+				summary: "Delete your account, or a user's as an admin, with the feeds added by it",
+				args:    []argDef{{name: "username", optional: true, complete: completeUsernames}},
+				flags:   []flagDef{{name: "yes", short: "y", usage: "Do not ask for confirmation"}},
+				handler: middlewareLoggedIn(handlerUserDelete),
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:    "whoami",
+		summary: "Show who you are logged in as, with your follows, feeds added, unread posts and account age",
+		handler: middlewareLoggedIn(handlerWhoami),
+	})
+	c.register(&commandDef{
+		name:    "reset",
+		summary: "Delete all users, posts and feeds, or only posts or one user (admins only)",
+		flags: []flagDef{
+			{name: "posts-only", usage: "Delete every post but keep users and feeds"},
+			{name: "user", value: "username", usage: "Delete one user and the feeds they added", complete: completeUsernames},
+			{name: "yes", short: "y", usage: "Do not ask for confirmation"},
+		},
+		handler: middlewareAdmin(handlerReset),
+	})
+	c.register(&commandDef{
+		name:    "admin",
+		summary: "Manage who administers the database (admins only)",
+		subcommands: []*commandDef{
+			{
+				name:    "grant",
+				summary: "Make a user an admin",
+				args:    []argDef{{name: "username", complete: completeUsernames}},
+				handler: middlewareAdmin(handlerAdminGrant),
+			},
+			{
+				name:    "revoke",
+				summary: "Take admin rights away from a user",
+				args:    []argDef{{name: "username", complete: completeUsernames}},
+				handler: middlewareAdmin(handlerAdminRevoke),
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:       "migrate",
+		summary:    "Upgrade, roll back or inspect the database schema",
+		skipSchema: true,
+		subcommands: []*commandDef{
+			{
+				name:    "up",
+				summary: "Apply every pending migration",
+				handler: handlerMigrateUp,
+			},
+			{
+				name:    "down",
+				summary: "Roll back the latest migration",
+				handler: handlerMigrateDown,
+			},
+			{
+				name:    "status",
+				summary: "List migrations and whether they are applied",
+				handler: handlerMigrateStatus,
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:       "config",
+		summary:    "Show or change settings",
+		skipSchema: true,
+		subcommands: []*commandDef{
+			{
+				name:    "show",
+				summary: "Show the settings in use and where each comes from, hiding the database password",
+				handler: handlerConfigShow,
+			},
+			{
+				name:    "set",
+				summary: "Change a setting in the config file",
+				args: []argDef{
+					{name: "key", complete: choices(config.Keys...)},
+					{name: "value"},
+				},
+				handler: handlerConfigSet,
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:       "profile",
+		summary:    "Manage profiles, each with its own database, user and defaults",
+		skipSchema: true,
+		subcommands: []*commandDef{
+			{
+				name:    "list",
+				summary: "List profiles, marking the active one",
+				handler: handlerProfileList,
+			},
+			{
+				name:    "use",
+				summary: "Make a profile the one used when --profile is not given",
+				args:    []argDef{{name: "name", complete: completeProfiles}},
+				handler: handlerProfileUse,
+			},
+			{
+				name:    "add",
+				summary: "Add a profile for a database",
+				args:    []argDef{{name: "name"}, {name: "db url"}},
+				flags:   []flagDef{{name: "use", usage: "Switch to the new profile"}},
+				handler: handlerProfileAdd,
+			},
+			{
+				name:    "rm",
+				summary: "Remove a profile",
+				args:    []argDef{{name: "name", complete: completeProfiles}},
+				handler: handlerProfileRemove,
+			},
+		},
+	})
+	c.register(&commandDef{
+		name:       "version",
+		summary:    "Show the version of the application",
+		skipSchema: true,
+		handler:    handlerVersion,
+	})
+	c.register(&commandDef{
+		name:       "help",
+		summary:    "List commands, or show help for one",
+		args:       []argDef{{name: "command", optional: true, variadic: true, complete: c.completeCommands}},
+		skipSchema: true,
+		handler:    c.handlerHelp,
+	})
+	c.register(&commandDef{
+		name:       "completion",
+		summary:    "Print a shell completion script",
+		args:       []argDef{{name: "shell", complete: choices("bash", "zsh", "fish")}},
+		skipSchema: true,
+		handler:    handlerCompletion,
+	})
+	c.register(&commandDef{
+		name:       "__complete",
+		summary:    "Complete the words typed so far, called by the completion scripts",
+		args:       []argDef{{name: "word", optional: true, variadic: true}},
+		skipSchema: true,
+		hidden:     true,
+		handler:    c.handlerComplete,
+	})
+	return c
+}
+
+func (c *commands) register(def *commandDef) {
+	setParents(def)
+	c.defs = append(c.defs, def)
+}
+
+func setParents(def *commandDef) {
+	for _, sub := range def.subcommands {
+		sub.parent = def
+		setParents(sub)
+	}
+}
+
+func (c *commands) lookup(name string) *commandDef {
+	for _, def := range c.defs {
+		if def.name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// resolve follows cmd's name and leading arguments down to the command they name,
+// returning it with the remaining arguments
+func (c *commands) resolve(cmd command) (*commandDef, []string, error) {
+	def := c.lookup(cmd.name)
+	if def == nil {
+		return nil, nil, usageErrorf("error: command '%v' does not exist, use 'gator help' to list commands", cmd.name)
 	}
-	c.register("addfeed", middlewareLoggedIn(handlerAddFeed))
-	c.register("following", middlewareLoggedIn(handlerFollowing))
-	c.register("follow", middlewareLoggedIn(handlerFollow))
-	c.register("unfollow", middlewareLoggedIn(handlerUnfollow))
-	c.register("agg", middlewareLoggedIn(handlerAgg))
-	c.register("browse", middlewareLoggedIn(handlerBrowse))
-	c.register("login", handlerLogin)
-	c.register("register", handlerRegister)
-	c.register("reset", handlerReset)
-	c.register("users", handlerUsers)
-	c.register("feeds", handlerFeeds)
-	c.register("help", handlerHelp)
-	c.register("version", handlerVersion)
-	return &c
+	args := cmd.args
+	for len(def.subcommands) > 0 {
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			return def, args, nil
+		}
+		sub := def.subcommand(args[0])
+		if sub == nil {
+			return nil, nil, usageErrorf("error: '%v' has no subcommand '%v' \nusage: %v", def.path(), args[0], def.usage())
+		}
+		def, args = sub, args[1:]
+	}
+	return def, args, nil
 }
 
-func (c *commands) register(name string, f func(*state, command) error) {
-	c.registry[name] = f
+// needsSchema reports whether cmd must wait for the database schema to be up to date
+func (c *commands) needsSchema(cmd command) bool {
+	def := c.lookup(cmd.name)
+	return def != nil && !def.skipSchema
 }
 
 func (c *commands) run(s *state, cmd command) error {
-	if function, ok := c.registry[cmd.name]; !ok {
-		return fmt.Errorf("error: command '%v' does not exist", cmd.name)
-	} else {
-		err := function(s, cmd)
+	def, args, err := c.resolve(cmd)
+	if err != nil {
+		return err
+	}
+	parsed, err := def.parse(args)
+	if err != nil {
+		return err
+	}
+	if parsed.has(helpFlag.name) {
+		writeCommandHelp(s, def)
+		return nil
+	}
+	if def.handler == nil {
+		return usageErrorf("usage: %v", def.usage())
+	}
+	return def.handler(s, parsed)
+}
+
+func (c *commands) handlerHelp(s *state, cmd command) error {
+	if len(cmd.args) > 0 {
+		def, rest, err := c.resolve(command{name: cmd.args[0], args: cmd.args[1:]})
 		if err != nil {
 			return err
 		}
+		if len(rest) > 0 {
+			return usageErrorf("error: '%v' has no subcommands \nusage: %v", def.path(), def.usage())
+		}
+		writeCommandHelp(s, def)
+		return nil
+	}
+	fmt.Fprintln(s.out, "Available commands:")
+	var list func(defs []*commandDef)
+	list = func(defs []*commandDef) {
+		for _, def := range defs {
+			if def.hidden {
+				continue
+			}
+			if len(def.subcommands) > 0 {
+				list(def.subcommands)
+				continue
+			}
+			fmt.Fprintf(s.out, "  %v - %v\n", def.usage(), def.summary)
+		}
 	}
+	list(c.defs)
+	fmt.Fprintln(s.out)
+	fmt.Fprintln(s.out, "Global flags:")
+	for _, f := range globalFlags {
+		fmt.Fprintf(s.out, "  %v - %v\n", f, f.usage)
+	}
+	fmt.Fprintln(s.out)
+	fmt.Fprintln(s.out, "Use 'gator help <command>' or 'gator <command> --help' for details of a command")
 	return nil
 }
+
+func writeCommandHelp(s *state, def *commandDef) {
+	fmt.Fprintf(s.out, "usage: %v\n\n", def.usage())
+	fmt.Fprintln(s.out, def.summary)
+	if len(def.subcommands) > 0 {
+		fmt.Fprintln(s.out)
+		fmt.Fprintln(s.out, "Subcommands:")
+		for _, sub := range def.subcommands {
+			fmt.Fprintf(s.out, "  %-10v %v\n", sub.name, sub.summary)
+		}
+	}
+	fmt.Fprintln(s.out)
+	fmt.Fprintln(s.out, "Flags:")
+	for _, f := range def.allFlags() {
+		fmt.Fprintf(s.out, "  %-24v %v\n", f, f.usage)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanInput(t *testing.T) {
+	cmd, opts, err := newCommands().cleanInput([]string{"gator", "-o", "json", "browse", "--output=csv", "5", "--", "--output"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.name != "browse" || fmt.Sprint(cmd.args) != "[5 -- --output]" || opts.output != "csv" {
+		t.Errorf("got command %v %v with output %v", cmd.name, cmd.args, opts.output)
+	}
+	for _, args := range [][]string{{"gator"}, {"gator", "feeds", "--output"}} {
+		if _, _, err := newCommands().cleanInput(args); exitCode(err) != exitUsage {
+			t.Errorf("%v: got exit code %v, want %v", args, exitCode(err), exitUsage)
+		}
+	}
+	if _, err := parseOutputFormat("xml"); exitCode(err) != exitUsage {
+		t.Errorf("unknown format: got exit code %v, want %v", exitCode(err), exitUsage)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	s := newTestState(t)
+	if err := run(t, s, "nope"); exitCode(err) != exitUsage {
+		t.Errorf("unknown command: got exit code %v, want %v", exitCode(err), exitUsage)
+	}
+	if err := run(t, s, "register"); exitCode(err) != exitUsage {
+		t.Errorf("missing argument: got exit code %v, want %v", exitCode(err), exitUsage)
+	}
+	if err := run(t, s, "login", "nobody"); exitCode(err) != exitError {
+		t.Errorf("failed command: got exit code %v, want %v", exitCode(err), exitError)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	tests := []struct {
+		golden string
+		args   []string
+	}{
+		{"help", []string{"help"}},
+		{"help_addfeed", []string{"help", "addfeed"}},
+		{"help_feed", []string{"feed", "--help"}},
+		{"help_export_opml", []string{"export", "opml", "-h"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			s := newTestState(t)
+			mustRun(t, s, tt.args[0], tt.args[1:]...)
+			assertGolden(t, tt.golden, s.out.(*bytes.Buffer).Bytes())
+		})
+	}
+}
+
+func TestArgumentValidation(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	tests := [][]string{
+		{"register"},
+		{"register", "bob", "carol"},
+		{"addfeed", "a", "b", "c"},
+		{"addfeed", "--seed=yes", "http://example.com"},
+		{"addfeed", "--nope", "http://example.com"},
+		{"feed"},
+		{"feed", "move", "a", "b"},
+		{"feed", "rename", "a"},
+		{"browse", "many"},
+		{"help", "nope"},
+		{"help", "users", "extra"},
+	}
+	for _, args := range tests {
+		err := run(t, s, args[0], args[1:]...)
+		if exitCode(err) != exitUsage {
+			t.Errorf("gator %v: got %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestFlagsAnywhere(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "--seed", srv.URL+"/rss.xml")
+	s.out.(*bytes.Buffer).Reset()
+	mustRun(t, s, "export", "opml", filepath.Join(t.TempDir(), "feeds.opml"), "--all")
+	if !strings.Contains(s.out.(*bytes.Buffer).String(), "Exported 1 feed(s)") {
+		t.Errorf("unexpected output: %v", s.out)
+	}
+}
+
+func TestCommandFlagsShadowGlobalFlags(t *testing.T) {
+	c := newCommands()
+	cmd, opts, err := c.cleanInput([]string{"gator", "reset", "--user", "bob", "--yes"})
+	if err != nil || fmt.Sprint(cmd.args) != "[--user bob --yes]" || opts.user != "" {
+		t.Errorf("got %v %+v %v, want --user left to reset", cmd, opts, err)
+	}
+	cmd, opts, err = c.cleanInput([]string{"gator", "--user", "bob", "reset", "--yes"})
+	if err != nil || fmt.Sprint(cmd.args) != "[--yes]" || opts.user != "bob" {
+		t.Errorf("got %v %+v %v, want a global --user before the command", cmd, opts, err)
+	}
+	cmd, opts, err = c.cleanInput([]string{"gator", "following", "--user=bob"})
+	if err != nil || len(cmd.args) != 0 || opts.user != "bob" {
+		t.Errorf("got %v %+v %v, want a global --user", cmd, opts, err)
+	}
+}
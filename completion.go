@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+)
+
+// completion is a value offered by shell completion
+type completion struct {
+	value       string
+	description string
+	// alias is matched against what was typed as well as value, e.g. a feed's name for its url
+	alias string
+}
+
+// completer lists the values an argument or flag can take.
+// args holds the positional arguments typed before the one being completed.
+type completer func(s *state, args []string) []completion
+
+// choices completes a fixed set of values
+func choices(values ...string) completer {
+	return func(s *state, args []string) []completion {
+		completions := make([]completion, len(values))
+		for i, v := range values {
+			completions[i] = completion{value: v}
+		}
+		return completions
+	}
+}
+
+// completeFeedURLs offers the url of every registered feed, described by its name
+func completeFeedURLs(s *state, args []string) []completion {
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return nil
+	}
+	completions := make([]completion, len(feeds))
+	for i, feed := range feeds {
+		completions[i] = completion{value: feed.Url, description: feed.Name, alias: feed.Name}
+	}
+	return completions
+}
+
+// completeFollowedURLs offers the url of every feed the current user follows
+func completeFollowedURLs(s *state, args []string) []completion {
+	user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUsername)
+	if err != nil {
+		return nil
+	}
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	completions := make([]completion, len(follows))
+	for i, follow := range follows {
+		completions[i] = completion{value: follow.FeedUrl, description: followTitle(follow), alias: followTitle(follow)}
+	}
+	return completions
+}
+
+// completeFeedName offers the current name of the feed given as the previous argument
+func completeFeedName(s *state, args []string) []completion {
+	if len(args) == 0 {
+		return nil
+	}
+	feed, err := s.db.GetFeedByURL(context.Background(), args[len(args)-1])
+	if err != nil {
+		return nil
+	}
+	return []completion{{value: feed.Name}}
+}
+
+// completeFolders offers the current user's folders
+func completeFolders(s *state, args []string) []completion {
+	user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUsername)
+	if err != nil {
+		return nil
+	}
+	folders, err := s.db.GetFoldersForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	completions := make([]completion, len(folders))
+	for i, folder := range folders {
+		completions[i] = completion{value: folder.Name}
+	}
+	return completions
+}
+
+func completeUsernames(s *state, args []string) []completion {
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return nil
+	}
+	completions := make([]completion, len(users))
+	for i, user := range users {
+		completions[i] = completion{value: user}
+	}
+	return completions
+}
+
+// completeProfiles offers the profiles of the config file
+func completeProfiles(s *state, args []string) []completion {
+	completions := []completion{}
+	for _, name := range s.cfg.Profiles() {
+		completions = append(completions, completion{value: name})
+	}
+	return completions
+}
+
+// completePostIDs offers the ids of the current user's latest posts, described by their titles
+func completePostIDs(s *state, args []string) []completion {
+	return completePosts(s, false)
+}
+
+// completeHiddenPostIDs offers the ids of the latest posts the current user hid
+func completeHiddenPostIDs(s *state, args []string) []completion {
+	return completePosts(s, true)
+}
+
+// completeTags offers the tags the current user's rules gave posts
+func completeTags(s *state, args []string) []completion {
+	user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUsername)
+	if err != nil {
+		return nil
+	}
+	tags, err := s.db.GetTagsForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	return choices(tags...)(s, args)
+}
+
+// completeRuleIDs offers the ids of the current user's rules, described by what they do
+func completeRuleIDs(s *state, args []string) []completion {
+	user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUsername)
+	if err != nil {
+		return nil
+	}
+	rules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	completions := make([]completion, len(rules))
+	for i, rule := range rules {
+		completions[i] = completion{value: rule.ID.String(), description: compiledRule{Rule: ruleOf(rule)}.String()}
+	}
+	return completions
+}
+
+func completePosts(s *state, hidden bool) []completion {
+	user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUsername)
+	if err != nil {
+		return nil
+	}
+	posts, err := s.db.GetPostsForUser(
+		context.Background(),
+		database.GetPostsForUserParams{
+			UserID: user.ID,
+			Hidden: hidden,
+			Limit:  100,
+		},
+	)
+	if err != nil {
+		return nil
+	}
+	completions := make([]completion, len(posts))
+	for i, post := range posts {
+		completions[i] = completion{value: post.ID.String(), description: post.Title}
+	}
+	return completions
+}
+
+// completeCommands offers the commands, or the subcommands of the command named by args
+func (c *commands) completeCommands(s *state, args []string) []completion {
+	defs := c.defs
+	if len(args) > 0 {
+		def, rest, err := c.resolve(command{name: args[0], args: args[1:]})
+		if err != nil || len(rest) > 0 {
+			return nil
+		}
+		defs = def.subcommands
+	}
+	completions := []completion{}
+	for _, def := range defs {
+		if !def.hidden {
+			completions = append(completions, completion{value: def.name, description: def.summary})
+		}
+	}
+	return completions
+}
+
+func flagCompletions(flags []flagDef) []completion {
+	completions := make([]completion, len(flags))
+	for i, f := range flags {
+		completions[i] = completion{value: "--" + f.name, description: f.usage}
+	}
+	return completions
+}
+
+// complete returns what the last of words, the command line after 'gator', can be completed to
+func (c *commands) complete(s *state, words []string) []completion {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+	typed := []string{}
+	for i := 0; i < len(words)-1; i++ {
+		f, _, hasValue, ok := globalFlag(words[i])
+		if !ok {
+			typed = append(typed, words[i])
+			continue
+		}
+		if !hasValue && f.value != "" {
+			if i+1 == len(words)-1 {
+				return filterCompletions(f.complete, s, nil, current)
+			}
+			i++
+		}
+	}
+
+	if len(typed) == 0 {
+		if strings.HasPrefix(current, "-") {
+			return matching(flagCompletions(globalFlags), current)
+		}
+		return matching(c.completeCommands(s, nil), current)
+	}
+	def, rest, err := c.resolve(command{name: typed[0], args: typed[1:]})
+	if err != nil {
+		return nil
+	}
+	if len(def.subcommands) > 0 {
+		if strings.HasPrefix(current, "-") {
+			return matching(flagCompletions(def.allFlags()), current)
+		}
+		return matching(c.completeCommands(s, typed), current)
+	}
+	args := []string{}
+	for i := 0; i < len(rest); i++ {
+		if rest[i] == "--" {
+			args = append(args, rest[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(rest[i], "-") {
+			args = append(args, rest[i])
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(rest[i], "-"), "=")
+		f, ok := def.flagDef(name)
+		if ok && f.value != "" && !hasValue {
+			if i+1 == len(rest) {
+				return filterCompletions(f.complete, s, args, current)
+			}
+			i++
+		}
+	}
+	if strings.HasPrefix(current, "-") {
+		return matching(flagCompletions(def.allFlags()), current)
+	}
+	arg, ok := def.arg(len(args))
+	if !ok {
+		return nil
+	}
+	return filterCompletions(arg.complete, s, args, current)
+}
+
+func filterCompletions(complete completer, s *state, args []string, current string) []completion {
+	if complete == nil {
+		return nil
+	}
+	return matching(complete(s, args), current)
+}
+
+// matching keeps the completions whose value or alias starts with prefix, ignoring case for aliases
+func matching(completions []completion, prefix string) []completion {
+	matches := []completion{}
+	for _, c := range completions {
+		alias := c.alias != "" && strings.HasPrefix(strings.ToLower(c.alias), strings.ToLower(prefix))
+		if strings.HasPrefix(c.value, prefix) || alias {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// handlerComplete is called back by the completion scripts with the words typed so far
+func (c *commands) handlerComplete(s *state, cmd command) error {
+	for _, comp := range c.complete(s, cmd.args) {
+		if comp.description == "" {
+			fmt.Fprintln(s.out, comp.value)
+		} else {
+			fmt.Fprintf(s.out, "%v\t%v\n", comp.value, strings.ReplaceAll(comp.description, "\n", " "))
+		}
+	}
+	return nil
+}
+
+func handlerCompletion(s *state, cmd command) error {
+	script, ok := completionScripts[cmd.args[0]]
+	if !ok {
+		return usageErrorf("usage: gator completion bash|zsh|fish")
+	}
+	fmt.Fprint(s.out, script)
+	return nil
+}
+
+// Each script hands the words typed so far to 'gator __complete', which answers from the
+// command registry and the database, one 'value<TAB>description' per line.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for gator
+# source <(gator completion bash)
+_gator() {
+    local cur words cword
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n =: cur words cword
+    else
+        cur="${COMP_WORDS[COMP_CWORD]}"
+        words=("${COMP_WORDS[@]}")
+        cword=$COMP_CWORD
+    fi
+    local IFS=$'\n'
+    local line
+    COMPREPLY=()
+    for line in $(gator __complete -- "${words[@]:1:cword-1}" "$cur" 2>/dev/null); do
+        COMPREPLY+=("${line%%$'\t'*}")
+    done
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+complete -o default -F _gator gator
+`,
+	"zsh": `#compdef gator
+# gator completion zsh > "${fpath[1]}/_gator"
+_gator() {
+    local -a values descriptions
+    local line
+    for line in "${(@f)$(gator __complete -- "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null)}"; do
+        [[ -z $line ]] && continue
+        values+=("${line%%$'\t'*}")
+        if [[ $line == *$'\t'* ]]; then
+            descriptions+=("${line%%$'\t'*}  -- ${line#*$'\t'}")
+        else
+            descriptions+=("$line")
+        fi
+    done
+    (( ${#values} )) && compadd -U -l -d descriptions -a values
+}
+if [[ $zsh_eval_context[-1] == loadautofunc ]]; then
+    _gator "$@"
+else
+    compdef _gator gator
+fi
+`,
+	"fish": `# fish completion for gator
+# gator completion fish > ~/.config/fish/completions/gator.fish
+function __gator_complete
+    set -l words (commandline -opc)
+    gator __complete -- $words[2..-1] (commandline -ct) 2>/dev/null
+end
+complete -c gator -f -a '(__gator_complete)'
+`,
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{""}, nil},
+		{[]string{"foll"}, []string{"follow", "following"}},
+		{[]string{"feed", ""}, []string{"rm", "rename", "set-url", "history", "retention"}},
+		{[]string{"feed", "rm", "https://q"}, []string{"https://quiet.example.com/feed"}},
+		{[]string{"feed", "rm", "test"}, []string{"https://blog.example.com/rss.xml"}},
+		{[]string{"feed", "rename", "https://quiet.example.com/feed", ""}, []string{"Quiet Blog"}},
+		{[]string{"feed", "rename", "https://quiet.example.com/feed", "x", ""}, []string{}},
+		{[]string{"-o", "json", "login", ""}, []string{"alice", "bob"}},
+		{[]string{"feeds", "--output", "n"}, []string{"ndjson"}},
+		{[]string{"--o"}, []string{"--output"}},
+		{[]string{"addfeed", "--"}, []string{"--seed", "--help"}},
+		{[]string{"help", "export", ""}, []string{"opml"}},
+		{[]string{"completion", "z"}, []string{"zsh"}},
+		{[]string{"nope", ""}, []string{}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, c := range newCommands().complete(s, tt.words) {
+			got = append(got, c.value)
+		}
+		if tt.want == nil {
+			if len(got) < 15 || slices.Contains(got, "__complete") {
+				t.Errorf("%q: got %v, want every visible command", tt.words, got)
+			}
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestCompletePostIDs(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	got := matching(completePostIDs(s, nil), "00000000-0000-0000-0000-000000000001")
+	if len(got) != 1 || got[0].description != "First" {
+		t.Errorf("got %v, want the post titled First", got)
+	}
+}
+
+func TestCompleteCommandOutput(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	mustRun(t, s, "__complete", "--", "unfollow", "")
+	want := "https://quiet.example.com/feed\tQuiet Blog\nhttps://blog.example.com/rss.xml\tTest Blog\n"
+	if got := s.out.(*bytes.Buffer).String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		s.out.(*bytes.Buffer).Reset()
+		mustRun(t, s, "completion", shell)
+		if !strings.Contains(s.out.(*bytes.Buffer).String(), "gator __complete --") {
+			t.Errorf("%v script does not call back into gator", shell)
+		}
+	}
+	if err := run(t, s, "completion", "powershell"); exitCode(err) != exitUsage {
+		t.Errorf("unknown shell: got %v, want a usage error", err)
+	}
+}
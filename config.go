@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
+)
+
+// keyValuePassword matches the password of a 'key=value' style postgres connection string
+var keyValuePassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactDBUrl hides the password of a database url
+func redactDBUrl(dbURL string) string {
+	u, err := url.Parse(dbURL)
+	if err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			return u.String()
+		}
+	}
+	return keyValuePassword.ReplaceAllString(dbURL, "${1}xxxxx")
+}
+
+// configValue returns the setting named key ready to be shown
+func configValue(s *state, key string) (string, error) {
+	value, err := s.cfg.Get(key)
+	if err != nil {
+		return "", err
+	}
+	if key == config.KeyDBUrl {
+		value = redactDBUrl(value)
+	}
+	return value, nil
+}
+
+// validateConfigValue checks value suits the setting named key. An empty value unsets a default.
+func validateConfigValue(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	switch key {
+	case config.KeyOutput:
+		_, err := parseOutputFormat(value)
+		if err != nil {
+			return usageErrorf("usage: gator config set output table|json|ndjson|csv|yaml")
+		}
+	case config.KeyBrowseLimit:
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return usageErrorf("usage: gator config set browse_limit <number of posts>")
+		}
+	case config.KeyKeepDays, config.KeyKeepPosts:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return usageErrorf("usage: gator config set %v <number>, or '' to keep every post", key)
+		}
+	case config.KeyUnreadGraceDays:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return usageErrorf("usage: gator config set unread_grace_days <number of days>")
+		}
+	}
+	return nil
+}
+
+func handlerConfigShow(s *state, cmd command) error {
+	records := make([]configRecord, len(config.Keys))
+	for i, key := range config.Keys {
+		value, err := configValue(s, key)
+		if err != nil {
+			return err
+		}
+		source := s.cfg.Source(key)
+		if source == "" {
+			source = "file"
+		}
+		records[i] = configRecord{Key: key, Value: value, Source: source}
+	}
+	if s.format != outputTable {
+		return writeRecords(s, records)
+	}
+	fmt.Fprintln(s.out, "=============================CONFIG=============================")
+	fmt.Fprintf(s.out, "Config file: %v\n", s.cfg.Path())
+	fmt.Fprintf(s.out, "Profile:     %v\n", s.cfg.ProfileName())
+	for _, r := range records {
+		if r.Source == "file" {
+			fmt.Fprintf(s.out, "%-18v %v\n", r.Key+":", r.Value)
+		} else {
+			fmt.Fprintf(s.out, "%-18v %v (from %v)\n", r.Key+":", r.Value, r.Source)
+		}
+	}
+	fmt.Fprintln(s.out)
+	fmt.Fprintln(s.out, "================================================================")
+	return nil
+}
+
+func handlerConfigSet(s *state, cmd command) error {
+	key, value := cmd.args[0], cmd.args[1]
+	err := validateConfigValue(key, value)
+	if err != nil {
+		return err
+	}
+	source := s.cfg.Source(key)
+	err = s.cfg.Set(key, value)
+	if err != nil {
+		return err
+	}
+	shown, err := configValue(s, key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Set %v of profile '%v' to '%v' in %v\n", key, s.cfg.ProfileName(), shown, s.cfg.Path())
+	if source != "" {
+		fmt.Fprintf(s.errOut, "note: %v overrides it until unset\n", source)
+	}
+	return nil
+}
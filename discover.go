@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Paths probed when a page doesn't advertise its feed with a <link> tag
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss",
+	"/rss.xml",
+	"/atom.xml",
+	"/feed.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+var feedMIMETypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+type feedCandidate struct {
+	Title string
+	URL   string
+	// feed is the parsed feed when discovery already fetched it, nil for a feed only linked to
+	feed *RSSFeed
+}
+
+// discoverFeeds returns the feeds available at pageURL.
+// If pageURL is itself a feed it is the only candidate, otherwise the page's
+// alternate links are returned, falling back to probing commonFeedPaths.
+func discoverFeeds(ctx context.Context, pageURL string) ([]feedCandidate, error) {
+	body, location, err := fetchURL(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	feed, err := parseFeed(body)
+	if err == nil {
+		return []feedCandidate{{Title: feed.Channel.Title, URL: location, feed: feed}}, nil
+	}
+	if !errors.Is(err, errNotAFeed) {
+		return nil, err
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("error: invalid url '%v' \n%v", pageURL, err)
+	}
+	candidates, err := feedLinks(body, base)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		probe := base.ResolveReference(&url.URL{Path: path}).String()
+		feed, location, err := fetchFeed(ctx, probe)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, feedCandidate{Title: feed.Channel.Title, URL: location, feed: feed})
+	}
+	return candidates, nil
+}
+
+// feedLinks collects <link rel="alternate"> elements with a feed MIME type from an HTML document
+func feedLinks(body []byte, base *url.URL) ([]feedCandidate, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error: could not parse html \n%v", err)
+	}
+	var candidates []feedCandidate
+	seen := make(map[string]bool)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "base" {
+			if href := attr(n, "href"); href != "" {
+				if u, err := base.Parse(href); err == nil {
+					base = u
+				}
+			}
+		}
+		if n.Type == html.ElementNode && n.Data == "link" {
+			rels := strings.Fields(strings.ToLower(attr(n, "rel")))
+			href := attr(n, "href")
+			if slices.Contains(rels, "alternate") && isFeedMIMEType(attr(n, "type")) && href != "" {
+				if u, err := base.Parse(href); err == nil && !seen[u.String()] {
+					seen[u.String()] = true
+					candidates = append(candidates, feedCandidate{Title: attr(n, "title"), URL: u.String()})
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return candidates, nil
+}
+
+// isFeedMIMEType reports whether a type attribute, such as 'application/rss+xml; charset=utf-8', names a feed
+func isFeedMIMEType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	// A malformed parameter still leaves a usable media type
+	if err != nil && !errors.Is(err, mime.ErrInvalidMediaParameter) {
+		return false
+	}
+	return feedMIMETypes[strings.ToLower(mediaType)]
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// chooseFeed asks the user to pick one of several candidates
+func chooseFeed(s *state, candidates []feedCandidate) (feedCandidate, error) {
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	fmt.Fprintln(s.out, "Multiple feeds found:")
+	for i, c := range candidates {
+		title := c.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		fmt.Fprintf(s.out, "  [%v] %v - %v\n", i+1, title, c.URL)
+	}
+	fmt.Fprintf(s.out, "Choose a feed [1-%v]: ", len(candidates))
+	line, err := s.readLine()
+	if err != nil {
+		return feedCandidate{}, fmt.Errorf("error: no feed chosen")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(candidates) {
+		return feedCandidate{}, fmt.Errorf("error: invalid choice '%v'", strings.TrimSpace(line))
+	}
+	return candidates[n-1], nil
+}
+
+// resolveFeed turns the URL given to addfeed into a validated feed URL,
+// discovering the feed when a website URL is given.
+// A feed discovery already fetched is returned as is, only linked feeds are fetched here.
+func resolveFeed(ctx context.Context, s *state, pageURL string) (string, *RSSFeed, error) {
+	candidates, err := discoverFeeds(ctx, pageURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(candidates) == 0 {
+		return "", nil, fmt.Errorf("error: no feed found at %v; it is not an RSS, Atom or JSON feed and does not link to one", pageURL)
+	}
+	chosen, err := chooseFeed(s, candidates)
+	if err != nil {
+		return "", nil, err
+	}
+	if chosen.feed != nil {
+		return chosen.URL, chosen.feed, nil
+	}
+	feed, location, err := fetchFeed(ctx, chosen.URL)
+	if err != nil {
+		return "", nil, fmt.Errorf("error: %v is not a valid feed \n%v", chosen.URL, err)
+	}
+	return location, feed, nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+)
+
+func TestAddFeedDiscoversAndFollows(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", srv.URL+"/")
+
+	feed, err := s.db.GetFeedByURL(context.Background(), srv.URL+"/rss.xml")
+	if err != nil {
+		t.Fatalf("discovered feed was not stored: %v", err)
+	}
+	if feed.Name != "Test Blog" {
+		t.Errorf("name = %q, want the channel title", feed.Name)
+	}
+	if feed.Link.String != "https://blog.example.com/" || feed.Description.String != "Posts about testing" {
+		t.Errorf("channel link and description not stored: %+v", feed)
+	}
+	user, _ := s.db.GetUser(context.Background(), "alice")
+	follows, _ := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if len(follows) != 1 {
+		t.Fatalf("adding a feed should follow it, got %v follows", len(follows))
+	}
+
+	mustRun(t, s, "unfollow", srv.URL+"/rss.xml")
+	follows, _ = s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if len(follows) != 0 {
+		t.Fatalf("unfollow left %v follows", len(follows))
+	}
+	mustRun(t, s, "follow", srv.URL+"/rss.xml")
+	if err := run(t, s, "follow", srv.URL+"/rss.xml"); err == nil {
+		t.Error("following a feed twice should fail")
+	}
+}
+
+func TestAddFeedRejectsNonFeeds(t *testing.T) {
+	s := newTestState(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>No feeds here</title></head></html>`)
+	}))
+	defer srv.Close()
+	mustRun(t, s, "register", "alice")
+	if err := run(t, s, "addfeed", "Nothing", srv.URL+"/"); err == nil {
+		t.Fatal("adding a page without feeds should fail")
+	}
+	feeds, _ := s.db.GetFeeds(context.Background())
+	if len(feeds) != 0 {
+		t.Fatalf("no feed should be stored, got %v", feeds)
+	}
+}
+
+func TestFeedLinks(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/post.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		page string
+		want []feedCandidate
+	}{
+		{
+			name: "relative hrefs",
+			page: `<link rel="alternate" type="application/rss+xml" href="feed.xml">` +
+				`<link rel="alternate" type="application/atom+xml" href="/atom.xml" title="Atom">`,
+			want: []feedCandidate{
+				{URL: "https://example.com/blog/feed.xml"},
+				{Title: "Atom", URL: "https://example.com/atom.xml"},
+			},
+		},
+		{
+			name: "relative to base",
+			page: `<base href="https://cdn.example.com/site/"><link rel="alternate" type="application/rss+xml" href="rss">`,
+			want: []feedCandidate{{URL: "https://cdn.example.com/site/rss"}},
+		},
+		{
+			name: "multiple candidates",
+			page: `<link rel="alternate" type="application/rss+xml" href="/rss.xml" title="RSS">` +
+				`<link rel="alternate" type="application/feed+json" href="https://feeds.example.com/feed.json" title="JSON">` +
+				`<link rel="alternate" type="application/rss+xml" href="https://example.com/rss.xml" title="Duplicate">`,
+			want: []feedCandidate{
+				{Title: "RSS", URL: "https://example.com/rss.xml"},
+				{Title: "JSON", URL: "https://feeds.example.com/feed.json"},
+			},
+		},
+		{
+			name: "parameterised types",
+			page: `<link rel="Alternate Home" type="Application/RSS+XML; charset=UTF-8" href="/rss.xml">` +
+				`<link rel="alternate" type=" application/atom+xml ;charset=utf-8" href="/atom.xml">` +
+				`<link rel="alternate" type="application/feed+json; =" href="/feed.json">`,
+			want: []feedCandidate{
+				{URL: "https://example.com/rss.xml"},
+				{URL: "https://example.com/atom.xml"},
+				{URL: "https://example.com/feed.json"},
+			},
+		},
+		{
+			name: "no feeds",
+			page: `<link rel="stylesheet" type="text/css" href="/style.css">` +
+				`<link rel="alternate" type="text/html" href="/fr/" hreflang="fr">` +
+				`<link rel="icon" type="application/rss+xml" href="/not-alternate.xml">` +
+				`<link rel="alternate" type="application/rss+xml">` +
+				`<link rel="alternate" type="application/rss+xml/xml" href="/bad-type.xml">`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := "<html><head>" + tt.page + "</head><body></body></html>"
+			got, err := feedLinks([]byte(page), base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFeedFetchesOnce(t *testing.T) {
+	var hits atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		fmt.Fprint(w, testRSS)
+	})
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>No links here</title></head></html>`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	s := newTestState(t)
+
+	for _, pageURL := range []string{srv.URL + "/feed", srv.URL + "/"} {
+		hits.Store(0)
+		location, feed, err := resolveFeed(context.Background(), s, pageURL)
+		if err != nil {
+			t.Fatalf("%v: %v", pageURL, err)
+		}
+		if location != srv.URL+"/feed" || feed.Channel.Title != "Test Blog" {
+			t.Errorf("%v: got %v %+v, want the feed at /feed", pageURL, location, feed.Channel)
+		}
+		if n := hits.Load(); n != 1 {
+			t.Errorf("%v: feed fetched %v times, want once", pageURL, n)
+		}
+	}
+}
+
+func TestAddFeedUnescapesEntities(t *testing.T) {
+	const escapedRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<title>Tom &amp;amp; Jerry</title>
+<link>https://tom.example.com/</link>
+<description>Cats &amp;amp; mice</description>
+<item><title>Post &amp;amp; one</title><link>https://tom.example.com/1</link><description>First &amp;lt;post&amp;gt;</description></item>
+</channel></rss>`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, escapedRSS)
+	})
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><link rel="alternate" type="application/rss+xml" href="/rss.xml"></head></html>`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// The feed url itself is parsed during discovery, a page only links to it
+	for _, pageURL := range []string{srv.URL + "/rss.xml", srv.URL + "/"} {
+		s := newTestState(t)
+		mustRun(t, s, "register", "alice")
+		mustRun(t, s, "addfeed", pageURL, "--seed")
+		feed, err := s.db.GetFeedByURL(context.Background(), srv.URL+"/rss.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if feed.Name != "Tom & Jerry" || feed.Description.String != "Cats & mice" {
+			t.Errorf("%v: got name %q and description %q, want them unescaped", pageURL, feed.Name, feed.Description.String)
+		}
+		user, _ := s.db.GetUser(context.Background(), "alice")
+		posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{UserID: user.ID, Limit: 10})
+		if err != nil || len(posts) != 1 {
+			t.Fatalf("%v: got posts %+v %v, want the one item", pageURL, posts, err)
+		}
+		if post := posts[0]; post.Title != "Post & one" || post.Description.String != "First <post>" {
+			t.Errorf("%v: got post %q %q, want it unescaped", pageURL, post.Title, post.Description.String)
+		}
+	}
+}
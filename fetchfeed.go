@@ -1,35 +1,78 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
 	"net/http"
+	"strings"
 )
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+var errNotAFeed = errors.New("error: document is not an RSS, Atom or JSON feed")
+
+// fetchURL returns the body found at url along with the url it should be requested from in future.
+// That is the last location reached only through permanent (301/308) redirects, or url itself.
+func fetchURL(ctx context.Context, url string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	req.Header.Set("User-Agent", "gator")
-	client := http.DefaultClient
+	location := url
+	permanent := true
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			code := req.Response.StatusCode
+			permanent = permanent && (code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect)
+			if permanent {
+				location = req.URL.String()
+			}
+			return nil
+		},
+	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error: could not perform request \n%v", err)
+		return nil, "", fmt.Errorf("error: could not perform request \n%v", err)
 	}
 
 	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, "", fmt.Errorf("error: %v responded with %v", url, resp.Status)
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error: could not read response body")
+		return nil, "", fmt.Errorf("error: could not read response body")
+	}
+	return body, location, nil
+}
+
+// fetchFeed fetches and parses the feed at feedURL, returning the url the feed has permanently moved to
+// (feedURL if it hasn't)
+func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, string, error) {
+	body, location, err := fetchURL(ctx, feedURL)
+	if err != nil {
+		return nil, "", err
 	}
-	feed := RSSFeed{}
-	err = xml.Unmarshal(body, &feed)
+	feed, err := parseFeed(body)
 	if err != nil {
-		return nil, fmt.Errorf("error: could not unmarshal http response unto RSSFeed struct \n%v", err)
+		return nil, "", err
+	}
+	return feed, location, nil
+}
+
+// parseFeed unmarshals body with decodeFeed and unescapes the HTML entities left in its titles and descriptions
+func parseFeed(body []byte) (*RSSFeed, error) {
+	feed, err := decodeFeed(body)
+	if err != nil {
+		return nil, err
 	}
 	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
@@ -37,5 +80,55 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
 		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
 	}
-	return &feed, err
+	return feed, nil
+}
+
+// decodeFeed detects the format of body and unmarshals RSS, Atom and JSON feeds into an RSSFeed.
+// Anything else, such as an HTML page, returns errNotAFeed.
+func decodeFeed(body []byte) (*RSSFeed, error) {
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		feed := JSONFeed{}
+		err := json.Unmarshal(trimmed, &feed)
+		if err != nil || !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+			return nil, errNotAFeed
+		}
+		return feed.toRSS(), nil
+	}
+
+	root, err := rootElement(trimmed)
+	if err != nil {
+		return nil, errNotAFeed
+	}
+	switch root {
+	case "rss":
+		feed := RSSFeed{}
+		err = xml.Unmarshal(trimmed, &feed)
+		if err != nil {
+			return nil, fmt.Errorf("error: could not unmarshal http response unto RSSFeed struct \n%v", err)
+		}
+		return &feed, nil
+	case "feed":
+		feed := AtomFeed{}
+		err = xml.Unmarshal(trimmed, &feed)
+		if err != nil {
+			return nil, fmt.Errorf("error: could not unmarshal http response unto AtomFeed struct \n%v", err)
+		}
+		return feed.toRSS(), nil
+	}
+	return nil, errNotAFeed
+}
+
+func rootElement(body []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// getFolder looks up the folder of user called name
+func getFolder(s *state, user database.User, name string) (database.Folder, error) {
+	folder, err := s.db.GetFolder(
+		context.Background(),
+		database.GetFolderParams{
+			UserID: user.ID,
+			Name:   name,
+		},
+	)
+	if err != nil {
+		return database.Folder{}, fmt.Errorf("error: you have no folder '%v', use 'gator folder add %v' to create it", name, name)
+	}
+	return folder, nil
+}
+
+// checkFolderFree errors if user already has a folder called name
+func checkFolderFree(s *state, user database.User, name string) error {
+	_, err := getFolder(s, user, name)
+	if err == nil {
+		return fmt.Errorf("error: you already have a folder '%v'", name)
+	}
+	return nil
+}
+
+func handlerFolderAdd(s *state, cmd command, user database.User) error {
+	name := cmd.args[0]
+	err := checkFolderFree(s, user, name)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.CreateFolder(
+		context.Background(),
+		database.CreateFolderParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			Name:      name,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not create folder '%v' \n%v", name, err)
+	}
+	fmt.Fprintf(s.out, "Created folder '%v', use 'gator folder move <url> %v' to file feeds in it\n", name, name)
+	return nil
+}
+
+func handlerFolderRename(s *state, cmd command, user database.User) error {
+	folder, err := getFolder(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	name := cmd.args[1]
+	err = checkFolderFree(s, user, name)
+	if err != nil {
+		return err
+	}
+	err = s.db.RenameFolder(
+		context.Background(),
+		database.RenameFolderParams{
+			ID:        folder.ID,
+			Name:      name,
+			UpdatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not rename folder '%v' \n%v", folder.Name, err)
+	}
+	fmt.Fprintf(s.out, "Renamed folder '%v' to '%v'\n", folder.Name, name)
+	return nil
+}
+
+func handlerFolderRemove(s *state, cmd command, user database.User) error {
+	folder, err := getFolder(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	err = s.db.DeleteFolder(context.Background(), folder.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not delete folder '%v' \n%v", folder.Name, err)
+	}
+	fmt.Fprintf(s.out, "Deleted folder '%v', the feeds in it are still followed\n", folder.Name)
+	return nil
+}
+
+// handlerFolderMove files a followed feed in a folder, or takes it out of its folder when none is given
+func handlerFolderMove(s *state, cmd command, user database.User) error {
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("error: feed '%v' not registered, use 'gator following' to see the feeds you follow", cmd.args[0])
+	}
+	folderID := uuid.NullUUID{}
+	folderName := ""
+	if len(cmd.args) == 2 {
+		folder, err := getFolder(s, user, cmd.args[1])
+		if err != nil {
+			return err
+		}
+		folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+		folderName = folder.Name
+	}
+	moved, err := s.db.SetFeedFollowFolder(
+		context.Background(),
+		database.SetFeedFollowFolderParams{
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+			FolderID:  folderID,
+			UpdatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not move '%v' \n%v", feed.Name, err)
+	}
+	if moved == 0 {
+		return fmt.Errorf("error: %v not following %v", user.Name, feed.Name)
+	}
+	if folderName == "" {
+		fmt.Fprintf(s.out, "Took '%v' out of its folder\n", feed.Name)
+		return nil
+	}
+	fmt.Fprintf(s.out, "Moved '%v' to folder '%v'\n", feed.Name, folderName)
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFolders(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	mustRun(t, s, "folder", "add", "News")
+	mustRun(t, s, "folder", "add", "Later")
+	if err := run(t, s, "folder", "add", "News"); err == nil || !strings.Contains(err.Error(), "already have") {
+		t.Errorf("got %v, want a taken folder name to be refused", err)
+	}
+	mustRun(t, s, "folder", "move", "https://blog.example.com/rss.xml", "News")
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "following")
+	assertGolden(t, "following_folders", s.out.(*bytes.Buffer).Bytes())
+
+	s.format = outputNDJSON
+	for folder, want := range map[string]int{"News": 3, "Later": 0} {
+		s.out = &bytes.Buffer{}
+		mustRun(t, s, "browse", "10", "--folder", folder)
+		if got := strings.Count(s.out.(*bytes.Buffer).String(), "\n"); got != want {
+			t.Errorf("%v: got %v posts, want %v", folder, got, want)
+		}
+	}
+	s.format = outputTable
+	if err := run(t, s, "browse", "--folder", "Nope"); err == nil || !strings.Contains(err.Error(), "folder add Nope") {
+		t.Errorf("got %v, want an unknown folder to be refused", err)
+	}
+
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "export", "opml")
+	doc := OPML{}
+	if err := xml.Unmarshal(s.out.(*bytes.Buffer).Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	outlines := doc.Body.Outlines
+	if len(outlines) != 2 || outlines[0].Text != "Quiet Blog" || outlines[1].Text != "News" ||
+		len(outlines[1].Outlines) != 1 || outlines[1].Outlines[0].Text != "Test Blog" {
+		t.Errorf("feeds not grouped by folder: %+v", outlines)
+	}
+
+	mustRun(t, s, "folder", "rename", "News", "Reading")
+	if err := run(t, s, "folder", "rename", "Reading", "Later"); err == nil {
+		t.Error("renaming a folder to a taken name should fail")
+	}
+	follows, _ := s.db.GetFeedFollowsForUser(context.Background(), uuid.MustParse("00000000-0000-0000-0000-00000000a11c"))
+	if len(follows) != 2 || follows[1].FolderName.String != "Reading" {
+		t.Errorf("rename not applied to the follow: %+v", follows)
+	}
+	mustRun(t, s, "folder", "rm", "Reading")
+	follows, _ = s.db.GetFeedFollowsForUser(context.Background(), uuid.MustParse("00000000-0000-0000-0000-00000000a11c"))
+	if len(follows) != 2 || follows[1].FolderName.Valid {
+		t.Errorf("deleting the folder should leave its feeds followed and unfiled: %+v", follows)
+	}
+
+	// Folders belong to one user, and only followed feeds can be filed
+	mustRun(t, s, "login", "bob")
+	if err := run(t, s, "folder", "rm", "Later"); err == nil {
+		t.Error("another user's folder should not be found")
+	}
+	mustRun(t, s, "folder", "add", "Later")
+	if err := run(t, s, "folder", "move", "https://blog.example.com/rss.xml", "Later"); err == nil || !strings.Contains(err.Error(), "not following") {
+		t.Errorf("got %v, want moving an unfollowed feed to fail", err)
+	}
+}
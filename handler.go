@@ -1,11 +1,14 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
@@ -24,6 +27,13 @@ var dateFormats = []string{
 	"02 Jan 2006 15:04:05 MST",
 }
 
+func nullString(str string) sql.NullString {
+	return sql.NullString{
+		String: str,
+		Valid:  str != "",
+	}
+}
+
 func userExists(s *state, name string) bool {
 	_, err := s.db.GetUser(
 		context.Background(),
@@ -32,7 +42,7 @@ func userExists(s *state, name string) bool {
 	return err == nil
 }
 
-func formatPostPostParams(feedID uuid.UUID, post *RSSItem) (*database.PostPostParams, error) {
+func formatPostPostParams(s *state, feedID uuid.UUID, post *RSSItem) (*database.PostPostParams, error) {
 	var published_date time.Time
 	var err error
 
@@ -45,7 +55,7 @@ func formatPostPostParams(feedID uuid.UUID, post *RSSItem) (*database.PostPostPa
 
 	// If all formats failed, use current time
 	if err != nil {
-		fmt.Printf("Warning: could not parse date from %v (%s), using current time\n", post.Title, post.PubDate)
+		fmt.Fprintf(s.errOut, "Warning: could not parse date from %v (%s), using current time\n", post.Title, post.PubDate)
 		published_date = time.Now()
 	}
 
@@ -61,6 +71,8 @@ func formatPostPostParams(feedID uuid.UUID, post *RSSItem) (*database.PostPostPa
 		},
 		PublishedAt: published_date,
 		FeedID:      feedID,
+		Author:      nullString(strings.TrimSpace(post.author())),
+		Categories:  joinCategories(post.Categories),
 	}, nil
 }
 
@@ -84,52 +96,69 @@ func scrapeFeeds(s *state) error {
 	if err != nil {
 		return err
 	}
-	siteFeed, err := fetchFeed(context.Background(), dbfeed.Url)
+	siteFeed, location, err := fetchFeed(context.Background(), dbfeed.Url)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Title:       %v\n", siteFeed.Channel.Title)
-	fmt.Printf("Description: %v\n", siteFeed.Channel.Description)
-	fmt.Printf("Link:        %v\n", siteFeed.Channel.Link)
-	fmt.Println("============================CONTENT=============================")
+	if location != dbfeed.Url {
+		dbfeed, err = relocateFeed(s, dbfeed, location)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(s.out, "Title:       %v\n", siteFeed.Channel.Title)
+	fmt.Fprintf(s.out, "Description: %v\n", siteFeed.Channel.Description)
+	fmt.Fprintf(s.out, "Link:        %v\n", siteFeed.Channel.Link)
+	return savePosts(s, dbfeed.ID, siteFeed)
+}
 
+func savePosts(s *state, feedID uuid.UUID, siteFeed *RSSFeed) error {
+	fmt.Fprintln(s.out, "============================CONTENT=============================")
+
+	rules, err := feedRules(s, feedID)
+	if err != nil {
+		return err
+	}
 	for i := range siteFeed.Channel.Item {
-		fmt.Printf("Saving: %v...\n", siteFeed.Channel.Item[i].Title)
-		queryLoad, err := formatPostPostParams(dbfeed.ID, &siteFeed.Channel.Item[i])
+		fmt.Fprintf(s.out, "Saving: %v...\n", siteFeed.Channel.Item[i].Title)
+		queryLoad, err := formatPostPostParams(s, feedID, &siteFeed.Channel.Item[i])
 		if err != nil {
 			return err
 		}
 
-		_, err = s.db.PostPost(
+		post, err := s.db.PostPost(
 			context.Background(),
 			*queryLoad,
 		)
 
-		if err != nil {
-			if err.Error() == "pq: duplicate key value violates unique constraint" {
-				fmt.Printf("Post already exists: %v\n", siteFeed.Channel.Item[i].Title)
-			} else {
-				fmt.Printf("Error saving post: %v - %v\n", siteFeed.Channel.Item[i].Title, err)
-			}
+		// A post already saved from its url is left as it is, and PostPost returns no row
+		if errors.Is(err, sql.ErrNoRows) {
+			fmt.Fprintf(s.out, "Post already exists: %v\n", siteFeed.Channel.Item[i].Title)
+		} else if err != nil {
+			fmt.Fprintf(s.out, "Error saving post: %v - %v\n", siteFeed.Channel.Item[i].Title, err)
 		} else {
-			fmt.Printf("Saved: %v (%v)\n", siteFeed.Channel.Item[i].Title, siteFeed.Channel.Item[i].PubDate)
+			fmt.Fprintf(s.out, "Saved: %v (%v)\n", siteFeed.Channel.Item[i].Title, siteFeed.Channel.Item[i].PubDate)
+			applied, err := applyRules(s, rules, post)
+			for _, rule := range applied {
+				fmt.Fprintf(s.out, "Applied rule: %v\n", rule)
+			}
+			if err != nil {
+				fmt.Fprintln(s.out, err)
+			}
 		}
 	}
-	fmt.Println("Posts saved!")
+	fmt.Fprintln(s.out, "Posts saved!")
 	return nil
 }
 
 func handlerUnfollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("usage: gator unfollow '<link>'")
-	}
 	url := cmd.args[0]
 	feed, err := s.db.GetFeedByURL(
 		context.Background(),
 		url,
 	)
 	if err != nil {
-		fmt.Println("usage: gator unfollow '<link>'")
+		fmt.Fprintln(s.out, "usage: gator unfollow '<link>'")
 		return err
 	}
 	err = s.db.DeleteFeedFollow(
@@ -143,71 +172,127 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 	if err != nil {
 		return fmt.Errorf("error: %v not following %v", user.Name, feed.Name)
 	}
-	fmt.Println("================================================================")
-	fmt.Printf("%v unfollowed %v\n", user.Name, feed.Name)
-	fmt.Println("================================================================")
+	fmt.Fprintln(s.out, "================================================================")
+	fmt.Fprintf(s.out, "%v unfollowed %v\n", user.Name, feed.Name)
+	fmt.Fprintln(s.out, "================================================================")
 	return nil
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
 	limit := 2
-	err := errors.New("")
-	if len(cmd.args) > 1 {
-		return fmt.Errorf("usage: gator browse [limit]")
-	} else if len(cmd.args) == 1 {
+	if s.cfg.BrowseLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(s.cfg.BrowseLimit)
+		if err != nil {
+			return fmt.Errorf("error: browse_limit '%v' of profile '%v' is not a number", s.cfg.BrowseLimit, s.cfg.ProfileName())
+		}
+	}
+	if len(cmd.args) == 1 {
+		var err error
 		limit, err = strconv.Atoi(cmd.args[0])
 		if err != nil {
-			return fmt.Errorf("usage: gator browse [limit]")
+			return usageErrorf("usage: gator browse [<limit>] [--folder name] [--tag tag] [--hidden]")
 		}
 	}
+	folderID := uuid.NullUUID{}
+	if cmd.has("folder") {
+		folder, err := getFolder(s, user, cmd.flag("folder"))
+		if err != nil {
+			return err
+		}
+		folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
 	posts, err := s.db.GetPostsForUser(
 		context.Background(),
 		database.GetPostsForUserParams{
-			UserID: user.ID,
-			Limit:  int32(limit),
+			UserID:   user.ID,
+			FolderID: folderID,
+			Hidden:   cmd.has("hidden"),
+			Tag:      nullString(cmd.flag("tag")),
+			Limit:    int32(limit),
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("error: could not retreive posts \n%v", err)
 	}
-	for i := range posts {
-		fmt.Printf("Post: %v\n\n", posts[i].Title)
-		fmt.Printf("Link: %v\n\n", posts[i].Url)
-		fmt.Printf("Description: %v\n\n", posts[i].Description.String)
-		fmt.Println("================================================================")
+	records := make([]postRecord, len(posts))
+	for i, post := range posts {
+		tags, err := s.db.GetPostTags(
+			context.Background(),
+			database.GetPostTagsParams{
+				UserID: user.ID,
+				PostID: post.ID,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("error: could not retreive tags of '%v' \n%v", post.Title, err)
+		}
+		records[i] = newPostRecord(post, tags)
+	}
+	if s.format != outputTable {
+		return writeRecords(s, records)
+	}
+	for i, post := range records {
+		fmt.Fprintf(s.out, "Post: %v\n\n", post.Title)
+		fmt.Fprintf(s.out, "Feed: %v\n\n", post.FeedTitle)
+		if post.Author != nil {
+			fmt.Fprintf(s.out, "Author: %v\n\n", *post.Author)
+		}
+		fmt.Fprintf(s.out, "Link: %v\n\n", post.URL)
+		if len(post.Tags) > 0 {
+			fmt.Fprintf(s.out, "Tags: %v\n\n", strings.Join(post.Tags, ", "))
+		}
+		fmt.Fprintf(s.out, "Description: %v\n\n", posts[i].Description.String)
+		fmt.Fprintln(s.out, "================================================================")
 	}
 	return nil
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 2 {
-		return fmt.Errorf("usage: gator addfeed <name> <url>")
+	name, rawURL := "", cmd.args[0]
+	if len(cmd.args) == 2 {
+		name, rawURL = cmd.args[0], cmd.args[1]
+	}
+	url, siteFeed, err := resolveFeed(context.Background(), s, rawURL)
+	if err != nil {
+		return err
+	}
+	if url != rawURL {
+		fmt.Fprintf(s.out, "Using feed %v\n", url)
+	}
+	if name == "" {
+		name = siteFeed.Channel.Title
+	}
+	if name == "" {
+		return fmt.Errorf("error: feed has no title, use 'gator addfeed <name> <url>' to name it")
 	}
-	name := cmd.args[0]
-	url := cmd.args[1]
 	row, err := s.db.PostFeed(
 		context.Background(),
 		database.PostFeedParams{
-			ID:        uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Name:      name,
-			Url:       url,
-			UserID:    user.ID,
+			ID:          uuid.New(),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+			Name:        name,
+			Url:         url,
+			UserID:      user.ID,
+			Link:        nullString(siteFeed.Channel.Link),
+			Description: nullString(siteFeed.Channel.Description),
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("error posting feed: \n%v", err)
 	}
-	fmt.Println("Feed posted successfully!")
-	fmt.Println("================================================================")
-	fmt.Printf("ID:        %v\n", row.ID)
-	fmt.Printf("Created:   %v\n", row.CreatedAt)
-	fmt.Printf("Updated:   %v\n", row.UpdatedAt)
-	fmt.Printf("Title:     %v\n", row.Name)
-	fmt.Printf("Link:      %v\n", row.Url)
-	fmt.Printf("Posted by: %v / %v\n", user.Name, row.UserID)
-	fmt.Println("================================================================")
+	fmt.Fprintln(s.out, "Feed posted successfully!")
+	fmt.Fprintln(s.out, "================================================================")
+	fmt.Fprintf(s.out, "ID:        %v\n", row.ID)
+	fmt.Fprintf(s.out, "Created:   %v\n", row.CreatedAt)
+	fmt.Fprintf(s.out, "Updated:   %v\n", row.UpdatedAt)
+	fmt.Fprintf(s.out, "Title:     %v\n", row.Name)
+	fmt.Fprintf(s.out, "Link:      %v\n", row.Url)
+	fmt.Fprintf(s.out, "Site:      %v\n", row.Link.String)
+	fmt.Fprintf(s.out, "About:     %v\n", row.Description.String)
+	fmt.Fprintf(s.out, "Posted by: %v / %v\n", user.Name, row.UserID)
+	fmt.Fprintln(s.out, "================================================================")
 	_, err = s.db.CreateFeedFollow(
 		context.Background(),
 		database.CreateFeedFollowParams{
@@ -219,43 +304,138 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		},
 	)
 	if err != nil {
-		fmt.Println()
+		fmt.Fprintln(s.out)
 		return fmt.Errorf("error: feed not added to user's following \n%v", err)
 	}
-	return nil
+	if !cmd.has("seed") {
+		return nil
+	}
+	now := time.Now()
+	err = s.db.MarkFeedFetched(
+		context.Background(),
+		database.MarkFeedFetchedParams{
+			LastFetchedAt: sql.NullTime{
+				Time:  now,
+				Valid: true,
+			},
+			UpdatedAt: now,
+			ID:        row.ID,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return savePosts(s, row.ID, siteFeed)
 }
 
 func handlerFollowing(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 0 {
-		return fmt.Errorf("usage: gator following")
-	}
 	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
 	if err != nil {
 		return err
 	}
-	fmt.Println("=============================FOLLOWS============================")
-	fmt.Println()
+	if s.format != outputTable {
+		records := make([]followRecord, len(follows))
+		for i, follow := range follows {
+			records[i] = newFollowRecord(follow)
+		}
+		return writeRecords(s, records)
+	}
+	folders, err := s.db.GetFoldersForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not retreive folders \n%v", err)
+	}
+	// Feeds outside any folder come first, then each folder with its feeds
+	filed := make(map[string][]string)
+	fmt.Fprintln(s.out, "=============================FOLLOWS============================")
+	fmt.Fprintln(s.out)
+	listed := false
 	for _, feed := range follows {
-		fmt.Printf("Name: %v\n", feed.FeedName)
+		if feed.FolderName.Valid {
+			filed[feed.FolderName.String] = append(filed[feed.FolderName.String], followName(feed))
+			continue
+		}
+		fmt.Fprintf(s.out, "Name: %v\n", followName(feed))
+		listed = true
 	}
-	fmt.Println()
-	fmt.Println("================================================================")
+	for _, folder := range folders {
+		if listed {
+			fmt.Fprintln(s.out)
+		}
+		listed = true
+		fmt.Fprintf(s.out, "Folder: %v (%v)\n", folder.Name, len(filed[folder.Name]))
+		for _, name := range filed[folder.Name] {
+			fmt.Fprintf(s.out, "  Name: %v\n", name)
+		}
+	}
+	fmt.Fprintln(s.out)
+	fmt.Fprintln(s.out, "================================================================")
 	return nil
 }
 
-func handlerFollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("usage: gator follow '<link>'")
+// followTitle is the title user sees for a followed feed: their own title if they set one, else the feed's name
+func followTitle(follow database.GetFeedFollowsForUserRow) string {
+	return cmp.Or(follow.Title.String, follow.FeedName)
+}
+
+// followName is followTitle with the feed's name after it when the user retitled the feed
+func followName(follow database.GetFeedFollowsForUserRow) string {
+	if follow.Title.Valid {
+		return fmt.Sprintf("%v (%v)", follow.Title.String, follow.FeedName)
+	}
+	return follow.FeedName
+}
+
+// setFollowTitle sets user's own title for the feed, or clears it when title is empty
+func setFollowTitle(s *state, user database.User, feed database.Feed, title string) error {
+	updated, err := s.db.SetFeedFollowTitle(
+		context.Background(),
+		database.SetFeedFollowTitleParams{
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+			Title:     nullString(title),
+			UpdatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not set title of '%v' \n%v", feed.Name, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("error: %v not following %v", user.Name, feed.Name)
+	}
+	return nil
+}
+
+func handlerTitle(s *state, cmd command, user database.User) error {
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("error: feed '%v' not registered, use 'gator following' to see the feeds you follow", cmd.args[0])
+	}
+	title := ""
+	if len(cmd.args) == 2 {
+		title = strings.TrimSpace(cmd.args[1])
 	}
+	err = setFollowTitle(s, user, feed, title)
+	if err != nil {
+		return err
+	}
+	if title == "" {
+		fmt.Fprintf(s.out, "'%v' is shown by its name again\n", feed.Name)
+		return nil
+	}
+	fmt.Fprintf(s.out, "'%v' is shown to you as '%v'\n", feed.Name, title)
+	return nil
+}
+
+func handlerFollow(s *state, cmd command, user database.User) error {
 	url := cmd.args[0]
 	feed, err := s.db.GetFeedByURL(
 		context.Background(),
 		url,
 	)
 	if err != nil {
-		fmt.Println("feed not registered")
-		fmt.Println("use 'gator addfeed <name> <url>' to add feed")
-		fmt.Println("use 'gator feeds' see existing feeds")
+		fmt.Fprintln(s.out, "feed not registered")
+		fmt.Fprintln(s.out, "use 'gator addfeed <name> <url>' to add feed")
+		fmt.Fprintln(s.out, "use 'gator feeds' see existing feeds")
 		return err
 	}
 	row, err := s.db.CreateFeedFollow(
@@ -271,160 +451,231 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("================================================================")
-	fmt.Printf("%v now following %v!\n", row.UserName, row.FeedName)
-	fmt.Println("================================================================")
+	if cmd.flag("title") != "" {
+		err = setFollowTitle(s, user, feed, cmd.flag("title"))
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(s.out, "================================================================")
+	fmt.Fprintf(s.out, "%v now following %v!\n", row.UserName, row.FeedName)
+	fmt.Fprintln(s.out, "================================================================")
 	return nil
 }
 
 func handlerAgg(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("usage: gator <agg> '<refresh rate>'")
-	}
 	time_between_reqs, err := time.ParseDuration(cmd.args[0])
 	if err != nil {
-		return fmt.Errorf("usage: gator <agg> '<refresh rate i.e '1s'/'1m'/'1h'>'")
+		return usageErrorf("usage: gator agg <refresh rate>, e.g. '1s', '1m' or '1h'")
+	}
+	pruneEvery := time.Duration(0)
+	if cmd.has("prune-every") {
+		pruneEvery, err = time.ParseDuration(cmd.flag("prune-every"))
+		if err != nil || pruneEvery <= 0 {
+			return usageErrorf("usage: gator agg <refresh rate> --prune-every <interval>, e.g. '24h'")
+		}
+		if !hasAdminRights(user) {
+			return fmt.Errorf("error: only admins logged in with a password can prune posts, run agg without --prune-every")
+		}
 	}
 	cd := time.NewTicker(time_between_reqs)
-	fmt.Printf("Collecting feeds every %v\n", time_between_reqs)
+	fmt.Fprintf(s.out, "Collecting feeds every %v\n", time_between_reqs)
+	lastPrune := time.Time{}
 	for ; ; <-cd.C {
 		err := scrapeFeeds(s)
 		if err != nil {
 			return err
 		}
+		if pruneEvery > 0 && time.Since(lastPrune) >= pruneEvery {
+			err = prunePosts(s, false)
+			if err != nil {
+				return err
+			}
+			lastPrune = time.Now()
+		}
 	}
-	return nil
 }
 
 func handlerFeeds(s *state, cmd command) error {
-	if len(cmd.args) > 0 {
-		return fmt.Errorf("usage: gator feeds")
-	}
 	feeds, err := s.db.GetFeeds(context.Background())
 	if err != nil {
 		return err
 	}
-
-	fmt.Println("==============================FEEDS=============================")
-	for _, feed := range feeds {
+	records := make([]feedRecord, len(feeds))
+	for i, feed := range feeds {
 		user, err := s.db.GetUserById(context.Background(), feed.UserID)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Title:     %v\n", feed.Name)
-		fmt.Printf("Link:      %v\n", feed.Url)
-		fmt.Printf("ID:        %v\n", feed.ID)
-		fmt.Printf("User:      %v\n", user.Name)
-		fmt.Printf("Created:   %v\n", feed.CreatedAt)
-		fmt.Printf("Updated: %v\n", feed.UpdatedAt)
-		fmt.Printf("Last fetched: %v\n", feed.LastFetchedAt)
-		fmt.Println()
-		fmt.Println("================================================================")
+		records[i] = newFeedRecord(feed, user.Name)
+	}
+	if s.format != outputTable {
+		return writeRecords(s, records)
+	}
+
+	fmt.Fprintln(s.out, "==============================FEEDS=============================")
+	for _, feed := range records {
+		fmt.Fprintf(s.out, "Title:     %v\n", feed.Name)
+		fmt.Fprintf(s.out, "Link:      %v\n", feed.URL)
+		if feed.SiteURL != nil {
+			fmt.Fprintf(s.out, "Site:      %v\n", *feed.SiteURL)
+		} else {
+			fmt.Fprintln(s.out, "Site:      ")
+		}
+		fmt.Fprintf(s.out, "ID:        %v\n", feed.ID)
+		fmt.Fprintf(s.out, "User:      %v\n", feed.CreatedBy)
+		fmt.Fprintf(s.out, "Created:   %v\n", feed.CreatedAt)
+		fmt.Fprintf(s.out, "Updated: %v\n", feed.UpdatedAt)
+		if feed.LastFetchedAt != nil {
+			fmt.Fprintf(s.out, "Last fetched: %v\n", *feed.LastFetchedAt)
+		} else {
+			fmt.Fprintln(s.out, "Last fetched: never")
+		}
+		fmt.Fprintln(s.out)
+		fmt.Fprintln(s.out, "================================================================")
 
 	}
 	return nil
 }
 
 func handlerUsers(s *state, cmd command) error {
-	if len(cmd.args) > 0 {
-		return fmt.Errorf("usage: gator users")
-	}
 	users, err := s.db.GetUsers(context.Background())
 	if err != nil {
 		return fmt.Errorf("error: could not retreive users from db \n%v", err)
 	}
-	fmt.Println("=============================USERS=============================")
+	admins, err := s.db.GetAdmins(context.Background())
+	if err != nil {
+		return fmt.Errorf("error: could not retreive admins from db \n%v", err)
+	}
+	if s.format != outputTable {
+		records := make([]userRecord, len(users))
+		for i, user := range users {
+			records[i] = userRecord{Name: user, Current: user == s.cfg.CurrentUsername, Admin: slices.Contains(admins, user)}
+		}
+		return writeRecords(s, records)
+	}
+	fmt.Fprintln(s.out, "=============================USERS=============================")
 	for _, user := range users {
+		marks := []string{}
 		if user == s.cfg.CurrentUsername {
-			fmt.Printf("* %v (current)\n", user)
+			marks = append(marks, "current")
+		}
+		if slices.Contains(admins, user) {
+			marks = append(marks, "admin")
+		}
+		if len(marks) > 0 {
+			fmt.Fprintf(s.out, "* %v (%v)\n", user, strings.Join(marks, ", "))
 		} else {
-			fmt.Printf("* %v\n", user)
+			fmt.Fprintf(s.out, "* %v\n", user)
 		}
 	}
-	fmt.Println()
-	fmt.Println("================================================================")
+	fmt.Fprintln(s.out)
+	fmt.Fprintln(s.out, "================================================================")
 	return nil
 }
 
 func handlerLogin(s *state, cmd command) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("usage: gator login '<username>'")
-	}
 	username := cmd.args[0]
-	if !userExists(s, username) {
+	user, err := s.db.GetUser(context.Background(), username)
+	if err != nil {
 		return fmt.Errorf("error: user not registered")
 	}
-	err := s.cfg.SetUser(username)
+	err = checkPassword(s, user)
+	if err != nil {
+		return err
+	}
+	err = startSession(s, user)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Now logged in as %v\n", username)
+	fmt.Fprintf(s.out, "Now logged in as %v\n", username)
 	return nil
 }
 
 func handlerRegister(s *state, cmd command) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("usage: gator register '<username>'")
-	}
 	username := cmd.args[0]
 	if userExists(s, username) {
 		return fmt.Errorf("error: user exists")
 	}
+	passwordHash, err := askNewPassword(s, "Password (leave empty for none)")
+	if err != nil {
+		return err
+	}
+	// The first user administers the database
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return fmt.Errorf("error: could not retreive users from db \n%v", err)
+	}
 	u, err := s.db.CreateUser(
 		context.Background(),
 		database.CreateUserParams{
-			ID:        uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Name:      username,
+			ID:           uuid.New(),
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+			Name:         username,
+			PasswordHash: passwordHash,
+			IsAdmin:      len(users) == 0,
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("error: could not register user to database\n%v", err)
 	}
-	err = s.cfg.SetUser(u.Name)
+	err = startSession(s, u)
 	if err != nil {
 		return fmt.Errorf("error: user registered but not logged in\n%v", err)
 	}
-	fmt.Println("================================================================")
-	fmt.Printf("User '%v' created! \n", u.Name)
-	fmt.Printf("Created: %v \n", u.CreatedAt)
-	fmt.Printf("Updated: %v \n", u.UpdatedAt)
-	fmt.Printf("ID:      %v \n", u.ID)
-	fmt.Println("================================================================")
+	fmt.Fprintln(s.out, "================================================================")
+	fmt.Fprintf(s.out, "User '%v' created! \n", u.Name)
+	fmt.Fprintf(s.out, "Created: %v \n", u.CreatedAt)
+	fmt.Fprintf(s.out, "Updated: %v \n", u.UpdatedAt)
+	fmt.Fprintf(s.out, "ID:      %v \n", u.ID)
+	if u.IsAdmin && !u.PasswordHash.Valid {
+		fmt.Fprintln(s.out, "You are the admin, set a password with 'gator passwd' to use admin commands")
+	}
+	fmt.Fprintln(s.out, "================================================================")
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
-	if len(cmd.args) > 0 {
-		return fmt.Errorf("usage: gator reset")
+func handlerReset(s *state, cmd command, user database.User) error {
+	if cmd.has("posts-only") && cmd.has("user") {
+		return usageErrorf("usage: gator reset [--posts-only | --user <username>] [--yes]")
+	}
+	if cmd.has("posts-only") {
+		err := s.confirm(cmd, "This deletes every post of every feed, along with read and starred marks.")
+		if err != nil {
+			return err
+		}
+		postsDeleted, err := s.db.DeleteAllPosts(context.Background())
+		if err != nil {
+			return fmt.Errorf("error: posts table reset unsuccessful \n%v", err)
+		}
+		fmt.Fprintf(s.out, "Deleted %v post(s)\n", postsDeleted)
+		return nil
+	}
+	if cmd.has("user") {
+		target, err := s.db.GetUser(context.Background(), cmd.flag("user"))
+		if err != nil {
+			return fmt.Errorf("error: user '%v' not registered", cmd.flag("user"))
+		}
+		return deleteUser(s, cmd, target)
+	}
+	err := s.confirm(cmd, "This deletes every user, feed and post.")
+	if err != nil {
+		return err
 	}
 	usersDeleted, err := s.db.ResetUsers(context.Background())
 	if err != nil {
 		return fmt.Errorf("error: users table reset unsuccessful \n%v", err)
 	}
-	fmt.Printf("Deleted %v user(s)\n", usersDeleted)
+	err = s.cfg.SetUser("", "")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Deleted %v user(s)\n", usersDeleted)
 	return nil
 }
 
 func handlerVersion(s *state, cmd command) error {
-	fmt.Println("gator v0.1")
+	fmt.Fprintln(s.out, "gator v0.1")
 	return nil
 }
-func handlerHelp(s *state, cmd command) error {
-	fmt.Println("Available commands:")
-	fmt.Println("  gator register '<username>' - Register a new user")
-	fmt.Println("  gator login '<username>' - Log in as an existing user")
-	fmt.Println("  gator addfeed '<name>' '<url>' - Add a new feed")
-	fmt.Println("  gator follow '<link>' - Follow an existing feed")
-	fmt.Println("  gator unfollow '<link>' - Unfollow a feed")
-	fmt.Println("  gator browse [limit] - Browse posts with an optional limit (defaults to 2)")
-	fmt.Println("  gator feeds - List all feeds")
-	fmt.Println("  gator following - List feeds you are following")
-	fmt.Println("  gator users - List all users")
-	fmt.Println("  gator reset - Delete all users, posts and feeds")
-	fmt.Println("  gator version - Show the version of the application")
-	fmt.Println("  gator help - Show this help message")
-	return nil
-
-}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/ChipsAhoyEnjoyer/gator/internal/memory"
+	"github.com/google/uuid"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<title>Test Blog</title>
+<link>https://blog.example.com/</link>
+<description>Posts about testing</description>
+<item><title>First</title><link>https://blog.example.com/1</link><description>one</description><pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate></item>
+<item><title>Second</title><link>https://blog.example.com/2</link><description>two</description><pubDate>Tue, 03 Jan 2006 15:04:05 +0000</pubDate></item>
+</channel></rss>`
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// newTestState returns a state backed by an empty in-memory store, with output captured in buffers.
+// HOME points at a temporary directory so logging in never touches the real config file.
+func newTestState(t *testing.T) *state {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	for _, env := range []string{"GATOR_CONFIG", "GATOR_PROFILE", "GATOR_DB_URL", "GATOR_USER"} {
+		t.Setenv(env, "")
+	}
+	s := createStateInstance()
+	s.cfg = &config.Config{}
+	s.db = memory.New()
+	s.in = &bytes.Buffer{}
+	s.out = &bytes.Buffer{}
+	s.errOut = &bytes.Buffer{}
+	return s
+}
+
+// newSQLiteState is newTestState backed by a migrated SQLite database file instead
+func newSQLiteState(t *testing.T) *state {
+	t.Helper()
+	s := newTestState(t)
+	err := openStorage(s, sqliteScheme+filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.conn.Close() })
+	mustRun(t, s, "migrate", "up")
+	s.out = &bytes.Buffer{}
+	return s
+}
+
+// testBackends makes the states tests run against every storage with
+var testBackends = map[string]func(*testing.T) *state{
+	"memory": newTestState,
+	"sqlite": newSQLiteState,
+}
+
+// newFeedServer serves testRSS at /rss.xml, a page linking to it at /, and 404s elsewhere
+func newFeedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testRSS)
+	})
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><link rel="alternate" type="application/rss+xml" href="/rss.xml"></head></html>`)
+	})
+	mux.Handle("/old.xml", http.RedirectHandler("/rss.xml", http.StatusMovedPermanently))
+	mux.Handle("/temporary.xml", http.RedirectHandler("/rss.xml", http.StatusFound))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func run(t *testing.T, s *state, name string, args ...string) error {
+	t.Helper()
+	return newCommands().run(s, command{name: name, args: args})
+}
+
+func mustRun(t *testing.T, s *state, name string, args ...string) {
+	t.Helper()
+	err := run(t, s, name, args...)
+	if err != nil {
+		t.Fatalf("gator %v %v: %v", name, args, err)
+	}
+}
+
+// loginWithPassword logs in as name with the password 'secret', first setting it if they have none.
+// Admins need a password to use their rights.
+func loginWithPassword(t *testing.T, s *state, name string) {
+	t.Helper()
+	user, err := s.db.GetUser(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.PasswordHash.Valid {
+		s.in = strings.NewReader("secret\n")
+		mustRun(t, s, "login", name)
+		return
+	}
+	mustRun(t, s, "login", name)
+	s.in = strings.NewReader("secret\nsecret\n")
+	mustRun(t, s, "passwd")
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := newTestState(t)
+	mustRun(t, s, "register", "alice")
+	if s.cfg.CurrentUsername != "alice" {
+		t.Fatalf("registering should log in, current user is %q", s.cfg.CurrentUsername)
+	}
+	if err := run(t, s, "register", "alice"); err == nil {
+		t.Fatal("registering an existing user should fail")
+	}
+	mustRun(t, s, "register", "bob")
+	if err := run(t, s, "login", "carol"); err == nil {
+		t.Fatal("logging in as an unregistered user should fail")
+	}
+	mustRun(t, s, "login", "alice")
+	if s.cfg.CurrentUsername != "alice" {
+		t.Fatalf("current user is %q, want alice", s.cfg.CurrentUsername)
+	}
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil || len(users) != 2 {
+		t.Fatalf("GetUsers = %v, %v; want 2 users", users, err)
+	}
+}
+
+func TestLoggedInCommandsNeedAUser(t *testing.T) {
+	s := newTestState(t)
+	for _, name := range []string{"addfeed", "follow", "following", "browse", "export"} {
+		if err := run(t, s, name); err == nil {
+			t.Errorf("%v should fail without a logged in user", name)
+		}
+	}
+}
+
+func TestAddFeedSeedAndBrowse(t *testing.T) {
+	for name, newState := range testBackends {
+		t.Run(name, func(t *testing.T) {
+			testAddFeedSeedAndBrowse(t, newState(t))
+		})
+	}
+}
+
+func testAddFeedSeedAndBrowse(t *testing.T, s *state) {
+	srv := newFeedServer(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "My name", srv.URL+"/rss.xml", "--seed")
+
+	feed, _ := s.db.GetFeedByURL(context.Background(), srv.URL+"/rss.xml")
+	if feed.Name != "My name" {
+		t.Errorf("name = %q, want the given name", feed.Name)
+	}
+	if !feed.LastFetchedAt.Valid {
+		t.Error("seeding should mark the feed fetched")
+	}
+	posts, _ := s.db.CountPostsForFeed(context.Background(), feed.ID)
+	if posts != 2 {
+		t.Fatalf("seeding saved %v posts, want 2", posts)
+	}
+
+	// Fetching the feed again finds every post already saved, on every backend
+	s.out = &bytes.Buffer{}
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatal(err)
+	}
+	if out := s.out.(*bytes.Buffer).String(); strings.Count(out, "Post already exists") != 2 || strings.Contains(out, "Error saving post") {
+		t.Errorf("unexpected output fetching the feed again:\n%v", out)
+	}
+	if posts, _ := s.db.CountPostsForFeed(context.Background(), feed.ID); posts != 2 {
+		t.Errorf("got %v posts after fetching again, want 2", posts)
+	}
+	mustRun(t, s, "browse", "1")
+	if err := run(t, s, "browse", "many"); err == nil {
+		t.Error("browse with a non-numeric limit should fail")
+	}
+}
+
+// seedFixture fills s with users, a feed, follows and posts whose ids and times never change,
+// so the output of listing commands can be compared byte for byte.
+func seedFixture(t *testing.T, s *state) {
+	t.Helper()
+	ctx := context.Background()
+	at := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	alice, err := s.db.CreateUser(ctx, database.CreateUserParams{
+		ID:        uuid.MustParse("00000000-0000-0000-0000-00000000a11c"),
+		CreatedAt: at,
+		UpdatedAt: at,
+		Name:      "alice",
+		IsAdmin:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.db.CreateUser(ctx, database.CreateUserParams{
+		ID:        uuid.MustParse("00000000-0000-0000-0000-000000000b0b"),
+		CreatedAt: at,
+		UpdatedAt: at,
+		Name:      "bob",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeds := []database.PostFeedParams{
+		{
+			ID:          uuid.MustParse("00000000-0000-0000-0000-0000000000f1"),
+			CreatedAt:   at,
+			UpdatedAt:   at,
+			Name:        "Test Blog",
+			Url:         "https://blog.example.com/rss.xml",
+			UserID:      alice.ID,
+			Link:        nullString("https://blog.example.com/"),
+			Description: nullString("Posts about testing"),
+		},
+		{
+			ID:        uuid.MustParse("00000000-0000-0000-0000-0000000000f2"),
+			CreatedAt: at,
+			UpdatedAt: at,
+			Name:      "Quiet Blog",
+			Url:       "https://quiet.example.com/feed",
+			UserID:    alice.ID,
+		},
+	}
+	for i, arg := range feeds {
+		feed, err := s.db.PostFeed(ctx, arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:        uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-0000000001f%v", i+1)),
+			CreatedAt: at,
+			UpdatedAt: at,
+			UserID:    alice.ID,
+			FeedID:    feed.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = s.db.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
+		LastFetchedAt: sql.NullTime{Time: at.Add(time.Hour), Valid: true},
+		UpdatedAt:     at.Add(time.Hour),
+		ID:            feeds[0].ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, title := range []string{"First", "Second", "Third"} {
+		_, err := s.db.PostPost(ctx, database.PostPostParams{
+			ID:          uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-00000000000%v", i+1)),
+			CreatedAt:   at,
+			UpdatedAt:   at,
+			Title:       title,
+			Url:         fmt.Sprintf("https://blog.example.com/%v", i+1),
+			Description: nullString(fmt.Sprintf("Post number %v", i+1)),
+			PublishedAt: at.AddDate(0, 0, -3+i),
+			FeedID:      feeds[0].ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.cfg.CurrentUsername = "alice"
+}
+
+// assertGolden compares got with testdata/<name>.golden, rewriting the file when -update is set
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run 'go test -update' to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %v\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+// writeConfig writes data to the config file at the default location
+func writeConfig(t *testing.T, data string) {
+	t.Helper()
+	path, err := config.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrowseLimitDefault(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	s.cfg.BrowseLimit = "3"
+	s.format = outputNDJSON
+	mustRun(t, s, "browse")
+	if got := strings.Count(s.out.(*bytes.Buffer).String(), "\n"); got != 3 {
+		t.Errorf("got %v posts, want the profile's browse_limit of 3", got)
+	}
+}
+
+func TestFollowTitles(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	mustRun(t, s, "title", "https://blog.example.com/rss.xml", "Alice's blog")
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "following")
+	if !strings.Contains(s.out.(*bytes.Buffer).String(), "Name: Alice's blog (Test Blog)") {
+		t.Errorf("title not listed with the feed's name: %q", s.out.(*bytes.Buffer).String())
+	}
+	s.format = outputNDJSON
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "browse", "1")
+	if !strings.Contains(s.out.(*bytes.Buffer).String(), `"feed_title":"Alice's blog"`) {
+		t.Errorf("browse does not show the title: %q", s.out.(*bytes.Buffer).String())
+	}
+	// feeds keeps the name every user sees
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "feeds")
+	if !strings.Contains(s.out.(*bytes.Buffer).String(), `"name":"Test Blog"`) {
+		t.Errorf("feeds should show the feed's own name: %q", s.out.(*bytes.Buffer).String())
+	}
+	s.format = outputTable
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "export", "opml")
+	if !strings.Contains(s.out.(*bytes.Buffer).String(), `text="Alice&#39;s blog"`) {
+		t.Errorf("export does not use the title: %q", s.out.(*bytes.Buffer).String())
+	}
+
+	// Titles belong to one user
+	mustRun(t, s, "login", "bob")
+	if err := run(t, s, "title", "https://blog.example.com/rss.xml", "Mine"); err == nil || !strings.Contains(err.Error(), "not following") {
+		t.Errorf("got %v, want titling an unfollowed feed to fail", err)
+	}
+	mustRun(t, s, "follow", "https://blog.example.com/rss.xml", "--title", "Bob's pick")
+	s.format = outputCSV
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "following")
+	if got := s.out.(*bytes.Buffer).String(); !strings.Contains(got, ",Bob's pick\n") || strings.Contains(got, "Alice") {
+		t.Errorf("got %q, want only bob's title", got)
+	}
+	mustRun(t, s, "title", "https://blog.example.com/rss.xml")
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "following")
+	if got := s.out.(*bytes.Buffer).String(); !strings.Contains(got, ",Test Blog\n") {
+		t.Errorf("got %q, want the title cleared", got)
+	}
+}
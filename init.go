@@ -0,0 +1,69 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
+)
+
+// defaultDBUrl is offered by init, keeping everything in one file in the home directory
+const defaultDBUrl = "sqlite://~/.gator.db"
+
+// handlerInit sets gator up: it saves the database url to the config file, checks the
+// database can be reached, migrates it and registers or logs in the first user.
+// Values given with --db-url and --user (or their environment variables) are used as
+// they are; anything else is asked for.
+func handlerInit(s *state, cmd command) error {
+	dbURL := s.cfg.DBUrl
+	if s.cfg.Source(config.KeyDBUrl) == "" {
+		var err error
+		dbURL, err = s.prompt("Database url (postgres://... or sqlite:<path>)", cmp.Or(s.cfg.DBUrl, defaultDBUrl))
+		if err != nil {
+			return err
+		}
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	err := openStorage(s, dbURL)
+	if err != nil {
+		return err
+	}
+	err = s.conn.PingContext(context.Background())
+	if err != nil {
+		return fmt.Errorf("error: cannot connect to '%v' \n%v", redactDBUrl(dbURL), err)
+	}
+	err = s.cfg.Set(config.KeyDBUrl, dbURL)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Saved db_url to %v\n", s.cfg.Path())
+
+	err = handlerMigrateUp(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	username := s.cfg.CurrentUsername
+	if s.cfg.Source(config.KeyCurrentUsername) == "" {
+		username, err = s.prompt("Username to register or log in as (leave empty to skip)", s.cfg.CurrentUsername)
+		if err != nil {
+			return err
+		}
+	}
+	switch {
+	case username == "":
+		fmt.Fprintln(s.out, "No user set up, use 'gator register <username>' when you are ready")
+	case userExists(s, username):
+		err = handlerLogin(s, command{name: "login", args: []string{username}})
+	default:
+		err = handlerRegister(s, command{name: "register", args: []string{username}})
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.out, "Gator is ready, add a feed with 'gator addfeed <url>'")
+	return nil
+}
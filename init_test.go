@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
+)
+
+func TestInit(t *testing.T) {
+	s := newTestState(t)
+	dir := t.TempDir()
+	cfg, err := config.Read("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.cfg = cfg
+	s.in = strings.NewReader("sqlite:" + dir + "/one.db\nalice\n")
+	mustRun(t, s, "init")
+	out := s.out.(*bytes.Buffer).String()
+	for _, want := range []string{"Database url (postgres://... or sqlite:<path>) [sqlite://~/.gator.db]: ", "Database migrated to version", "User 'alice' created!", "Gator is ready"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%v", want, out)
+		}
+	}
+	if err := checkSchema(s); err != nil {
+		t.Error(err)
+	}
+	if cfg, _ := config.Read(""); cfg.DBUrl != "sqlite:"+dir+"/one.db" || cfg.CurrentUsername != "alice" {
+		t.Errorf("config not saved: %+v", cfg.Profile)
+	}
+
+	// Running it again keeps the saved values as defaults and logs in existing users
+	s.in = strings.NewReader("\n\n")
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "init")
+	if out := s.out.(*bytes.Buffer).String(); !strings.Contains(out, "Database is up to date") || !strings.Contains(out, "Now logged in as alice") {
+		t.Errorf("got:\n%v", out)
+	}
+
+	// Values given by flags are not asked for
+	s.cfg.Override(config.KeyDBUrl, "sqlite:"+dir+"/two.db", "--db-url")
+	s.cfg.Override(config.KeyCurrentUsername, "bob", "--user")
+	s.in = strings.NewReader("")
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "init")
+	if out := s.out.(*bytes.Buffer).String(); strings.Contains(out, "[") || !strings.Contains(out, "User 'bob' created!") {
+		t.Errorf("got:\n%v", out)
+	}
+	if cfg, _ := config.Read(""); cfg.DBUrl != "sqlite:"+dir+"/two.db" || cfg.CurrentUsername != "bob" {
+		t.Errorf("config not saved: %+v", cfg.Profile)
+	}
+	s.conn.Close()
+}
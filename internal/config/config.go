@@ -2,69 +2,419 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 const (
 	gatorConfigFile = ".gatorconfig.json"
+	// gatorConfigEnv names a config file to use instead of the default locations
+	gatorConfigEnv = "GATOR_CONFIG"
+	// gatorProfileEnv names the profile to use instead of the config file's
+	gatorProfileEnv = "GATOR_PROFILE"
+	xdgConfigDir    = "gator"
+	xdgConfigFile   = "config.json"
+
+	// DefaultProfile is the profile kept at the top level of the config file,
+	// as gator wrote it before it had profiles
+	DefaultProfile = "default"
+
+	KeyDBUrl           = "db_url"
+	KeyCurrentUsername = "current_user_name"
+	KeyOutput          = "output"
+	KeyBrowseLimit     = "browse_limit"
+	KeyKeepDays        = "keep_days"
+	KeyKeepPosts       = "keep_posts"
+	KeyUnreadGraceDays = "unread_grace_days"
 )
 
-type Config struct {
+// Keys lists the settings of a profile
+var Keys = []string{KeyDBUrl, KeyCurrentUsername, KeyOutput, KeyBrowseLimit, KeyKeepDays, KeyKeepPosts, KeyUnreadGraceDays}
+
+// envOverrides maps environment variables to the settings they override
+var envOverrides = []struct{ env, key string }{
+	{"GATOR_DB_URL", KeyDBUrl},
+	{"GATOR_USER", KeyCurrentUsername},
+}
+
+// Profile holds the settings for one database
+type Profile struct {
 	DBUrl           string `json:"db_url"`
 	CurrentUsername string `json:"current_user_name"`
+	// Output is the default of the --output flag
+	Output string `json:"output,omitempty"`
+	// BrowseLimit is the number of posts browse shows when given no limit
+	BrowseLimit string `json:"browse_limit,omitempty"`
+	// KeepDays and KeepPosts are how many days and posts of each feed prune keeps,
+	// for feeds without retention settings of their own
+	KeepDays  string `json:"keep_days,omitempty"`
+	KeepPosts string `json:"keep_posts,omitempty"`
+	// UnreadGraceDays is how long prune spares unread posts after they are saved
+	UnreadGraceDays string `json:"unread_grace_days,omitempty"`
+	// SessionToken proves the current user logged in with their password
+	SessionToken string `json:"session_token,omitempty"`
+}
+
+// file is the layout of the config file. Its top-level settings are the default profile,
+// so files written before profiles existed keep working.
+type file struct {
+	Profile
+	// ActiveProfile is the profile used when none is given, the default profile when empty
+	ActiveProfile string             `json:"profile,omitempty"`
+	Profiles      map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Config holds the settings of the profile in use
+type Config struct {
+	Profile
+	// profile is the name of the profile in use
+	profile string
+	file    file
+	// path is the file the config was read from and is written back to
+	path string
+	// overrides holds the settings replaced by Override, by key
+	overrides map[string]override
+}
+
+// override is a setting replaced for this run only
+type override struct {
+	// fileValue is written back to the config file in place of the overriding value
+	fileValue string
+	// source is what overrode the setting, e.g. 'GATOR_DB_URL'
+	source string
 }
 
-func Read() (*Config, error) {
-	configJsonPath, err := getConfigFilePath()
+// Read loads the named profile from the config file, then applies the overrides of
+// GATOR_DB_URL and GATOR_USER. Without a name, the profile comes from GATOR_PROFILE,
+// then the file's active profile. A missing file reads as an empty config, which is
+// created when first written.
+func Read(profile string) (*Config, error) {
+	configJsonPath, err := Path()
 	if err != nil {
 		return nil, err
 	}
+	c := &Config{path: configJsonPath}
 	jsonData, err := os.ReadFile(configJsonPath)
-	if err != nil {
-		return nil, fmt.Errorf("error: cannot read config file '%v': \n%v", jsonData, err)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error: cannot read config file '%v': \n%v", configJsonPath, err)
 	}
-	c := &Config{}
-	err = json.Unmarshal(jsonData, c)
-	if err != nil {
-		return nil, fmt.Errorf("error: cannot unmarshal json data to Config struct: \n%v", err)
+	if err == nil {
+		err = json.Unmarshal(jsonData, &c.file)
+		if err != nil {
+			return nil, fmt.Errorf("error: cannot unmarshal json data to Config struct: \n%v", err)
+		}
+	}
+	if profile == "" {
+		profile = os.Getenv(gatorProfileEnv)
+	}
+	if profile == "" {
+		profile = c.ActiveProfile()
+	}
+	if !c.HasProfile(profile) {
+		return nil, fmt.Errorf("error: profile '%v' does not exist in '%v', use one of: %v", profile, configJsonPath, strings.Join(c.Profiles(), ", "))
+	}
+	c.profile = profile
+	c.Profile = c.saved(profile)
+	for _, o := range envOverrides {
+		if value, ok := os.LookupEnv(o.env); ok && value != "" {
+			c.Override(o.key, value, o.env)
+		}
 	}
 	return c, nil
 }
 
-func (cfg *Config) SetUser(username string) error {
-	cfg.CurrentUsername = username
-	err := write(cfg)
+// SetUser saves username as the current user, along with the token of their session,
+// empty for users without a password
+func (cfg *Config) SetUser(username, sessionToken string) error {
+	cfg.SessionToken = sessionToken
+	err := cfg.Set(KeyCurrentUsername, username)
 	if err != nil {
 		return fmt.Errorf("error: cannot setting new user: \n%v", err)
 	}
 	return nil
 }
 
+// field returns the setting named key
+func (p *Profile) field(key string) (*string, error) {
+	switch key {
+	case KeyDBUrl:
+		return &p.DBUrl, nil
+	case KeyCurrentUsername:
+		return &p.CurrentUsername, nil
+	case KeyOutput:
+		return &p.Output, nil
+	case KeyBrowseLimit:
+		return &p.BrowseLimit, nil
+	case KeyKeepDays:
+		return &p.KeepDays, nil
+	case KeyKeepPosts:
+		return &p.KeepPosts, nil
+	case KeyUnreadGraceDays:
+		return &p.UnreadGraceDays, nil
+	}
+	return nil, fmt.Errorf("error: unknown config key '%v', use one of: %v", key, strings.Join(Keys, ", "))
+}
+
+// Get returns the value of the setting named key, including any override
+func (cfg *Config) Get(key string) (string, error) {
+	field, err := cfg.field(key)
+	if err != nil {
+		return "", err
+	}
+	return *field, nil
+}
+
+// Set changes the setting named key and saves it to the config file, dropping any override of it
+func (cfg *Config) Set(key, value string) error {
+	field, err := cfg.field(key)
+	if err != nil {
+		return err
+	}
+	*field = value
+	delete(cfg.overrides, key)
+	return write(cfg)
+}
+
+// Override replaces the setting named key for this run without saving it.
+// source names what overrode it, such as an environment variable or flag.
+func (cfg *Config) Override(key, value, source string) error {
+	field, err := cfg.field(key)
+	if err != nil {
+		return err
+	}
+	if cfg.overrides == nil {
+		cfg.overrides = map[string]override{}
+	}
+	o, ok := cfg.overrides[key]
+	if !ok {
+		o.fileValue = *field
+	}
+	o.source = source
+	cfg.overrides[key] = o
+	*field = value
+	return nil
+}
+
+// Source returns what overrode the setting named key, or "" when it comes from the config file
+func (cfg *Config) Source(key string) string {
+	return cfg.overrides[key].source
+}
+
+// Path returns the file the config was read from, or "" for a config that was not read from a file
+func (cfg *Config) Path() string {
+	return cfg.path
+}
+
+// ProfileName returns the name of the profile in use
+func (cfg *Config) ProfileName() string {
+	if cfg.profile == "" {
+		return DefaultProfile
+	}
+	return cfg.profile
+}
+
+// ActiveProfile returns the profile the config file uses when none is given
+func (cfg *Config) ActiveProfile() string {
+	if cfg.file.ActiveProfile == "" {
+		return DefaultProfile
+	}
+	return cfg.file.ActiveProfile
+}
+
+// Profiles returns the names of the profiles, the default profile first
+func (cfg *Config) Profiles() []string {
+	names := []string{}
+	for name := range cfg.file.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+func (cfg *Config) HasProfile(name string) bool {
+	_, ok := cfg.file.Profiles[name]
+	return ok || name == DefaultProfile
+}
+
+// SavedProfile returns the settings of the named profile as saved in the config file
+func (cfg *Config) SavedProfile(name string) (Profile, error) {
+	if !cfg.HasProfile(name) {
+		return Profile{}, fmt.Errorf("error: profile '%v' does not exist", name)
+	}
+	if name == cfg.ProfileName() {
+		return cfg.withoutOverrides(), nil
+	}
+	return cfg.saved(name), nil
+}
+
+// UseProfile makes the named profile the one used when none is given, and switches to it
+func (cfg *Config) UseProfile(name string) error {
+	if !cfg.HasProfile(name) {
+		return fmt.Errorf("error: profile '%v' does not exist", name)
+	}
+	cfg.storeProfile()
+	cfg.file.ActiveProfile = name
+	if name == DefaultProfile {
+		cfg.file.ActiveProfile = ""
+	}
+	cfg.switchProfile(name)
+	return write(cfg)
+}
+
+// AddProfile saves a new profile named name
+func (cfg *Config) AddProfile(name string, p Profile) error {
+	if cfg.HasProfile(name) {
+		return fmt.Errorf("error: profile '%v' already exists", name)
+	}
+	if name == "" {
+		return fmt.Errorf("error: profile name cannot be empty")
+	}
+	if cfg.file.Profiles == nil {
+		cfg.file.Profiles = map[string]Profile{}
+	}
+	cfg.file.Profiles[name] = p
+	return write(cfg)
+}
+
+// RemoveProfile deletes the named profile, which must not be the default one or the one in use
+func (cfg *Config) RemoveProfile(name string) error {
+	if !cfg.HasProfile(name) {
+		return fmt.Errorf("error: profile '%v' does not exist", name)
+	}
+	if name == DefaultProfile {
+		return fmt.Errorf("error: the %v profile cannot be removed", DefaultProfile)
+	}
+	if name == cfg.ProfileName() {
+		return fmt.Errorf("error: profile '%v' is in use, switch to another profile first", name)
+	}
+	delete(cfg.file.Profiles, name)
+	if cfg.file.ActiveProfile == name {
+		cfg.file.ActiveProfile = ""
+	}
+	return write(cfg)
+}
+
+// saved returns the named profile as read from the config file
+func (cfg *Config) saved(name string) Profile {
+	if name == DefaultProfile {
+		return cfg.file.Profile
+	}
+	return cfg.file.Profiles[name]
+}
+
+// withoutOverrides returns the settings in use as they are saved
+func (cfg *Config) withoutOverrides() Profile {
+	p := cfg.Profile
+	for key, o := range cfg.overrides {
+		field, _ := p.field(key)
+		*field = o.fileValue
+	}
+	return p
+}
+
+// storeProfile copies the settings in use, without their overrides, to the file layout
+func (cfg *Config) storeProfile() {
+	p := cfg.withoutOverrides()
+	if cfg.ProfileName() == DefaultProfile {
+		cfg.file.Profile = p
+		return
+	}
+	if cfg.file.Profiles == nil {
+		cfg.file.Profiles = map[string]Profile{}
+	}
+	cfg.file.Profiles[cfg.ProfileName()] = p
+}
+
+// switchProfile loads the named profile, keeping the overrides
+func (cfg *Config) switchProfile(name string) {
+	overrides, current := cfg.overrides, cfg.Profile
+	cfg.overrides = nil
+	cfg.profile = name
+	cfg.Profile = cfg.saved(name)
+	for key, o := range overrides {
+		value, _ := current.field(key)
+		cfg.Override(key, *value, o.source)
+	}
+}
+
+// write replaces the config file with a temporary file renamed over it, so that it is
+// never left partially written. The file is only readable by its owner as it holds the
+// database password.
 func write(cfg *Config) error {
-	jsonData, err := json.Marshal(cfg)
+	cfg.storeProfile()
+	jsonData, err := json.Marshal(cfg.file)
 	if err != nil {
 		return fmt.Errorf("error: cannot marshal json data from Config struct: \n%v", err)
 	}
-	configJsonPath, err := getConfigFilePath()
+	configJsonPath := cfg.path
+	if configJsonPath == "" {
+		configJsonPath, err = Path()
+		if err != nil {
+			return err
+		}
+	}
+	dir := filepath.Dir(configJsonPath)
+	err = os.MkdirAll(dir, 0700)
 	if err != nil {
-		return err
+		return fmt.Errorf("error: cannot create config directory '%v': \n%v", dir, err)
 	}
-	err = os.WriteFile(configJsonPath, jsonData, 0666)
+	tmp, err := os.CreateTemp(dir, filepath.Base(configJsonPath)+".*.tmp")
 	if err != nil {
-		return fmt.Errorf(
-			"error: cannot write Config struct to config file; File maybe be empty or partially overwritten: \n%v",
-			err,
-		)
+		return fmt.Errorf("error: cannot create temporary config file in '%v': \n%v", dir, err)
 	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(jsonData)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error: cannot write temporary config file '%v': \n%v", tmp.Name(), err)
+	}
+	err = os.Rename(tmp.Name(), configJsonPath)
+	if err != nil {
+		return fmt.Errorf("error: cannot replace config file '%v': \n%v", configJsonPath, err)
+	}
+	cfg.path = configJsonPath
 	return nil
 }
 
-func getConfigFilePath() (string, error) {
+// Path returns the config file gator uses, in order of precedence:
+//   - the file named by $GATOR_CONFIG
+//   - $XDG_CONFIG_HOME/gator/config.json (~/.config/gator/config.json when unset), if it exists
+//   - the legacy ~/.gatorconfig.json, if it exists
+//   - $XDG_CONFIG_HOME/gator/config.json otherwise
+func Path() (string, error) {
+	if path := os.Getenv(gatorConfigEnv); path != "" {
+		return path, nil
+	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("error: cannot retreive home directory\n%v", err)
 	}
-	return filepath.Join(homeDir, gatorConfigFile), nil
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" || !filepath.IsAbs(configHome) {
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	xdgPath := filepath.Join(configHome, xdgConfigDir, xdgConfigFile)
+	legacyPath := filepath.Join(homeDir, gatorConfigFile)
+	if exists(xdgPath) || !exists(legacyPath) {
+		return xdgPath, nil
+	}
+	return legacyPath, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
 }
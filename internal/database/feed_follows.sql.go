@@ -7,11 +7,24 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const countFollowsForFeed = `-- name: CountFollowsForFeed :one
+SELECT count(*) FROM feed_follows
+WHERE feed_id = $1
+`
+
+func (q *Queries) CountFollowsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFollowsForFeed, feedID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow  AS (
     INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
@@ -21,10 +34,10 @@ WITH inserted_feed_follow  AS (
         $3,
         $4,
         $5
-    ) RETURNING id, created_at, updated_at, user_id, feed_id
+    ) RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title
 )
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, feeds.name as feed_name, users.name as user_name
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.folder_id, inserted_feed_follow.title, feeds.name as feed_name, users.name as user_name
     FROM inserted_feed_follow
     INNER JOIN feeds
     ON feeds.id = inserted_feed_follow.feed_id
@@ -46,6 +59,8 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
 	FeedName  string
 	UserName  string
 }
@@ -65,6 +80,8 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -87,19 +104,27 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.feed_id, users.name AS user_name, feeds.name AS feed_name, feeds.url AS feed_url, folders.name AS folder_name, feed_follows.title
 FROM feed_follows
 INNER JOIN users
 ON feed_follows.user_id = users.id
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders
+ON feed_follows.folder_id = folders.id
 WHERE feed_follows.user_id = $1
+ORDER BY COALESCE(feed_follows.title, feeds.name)
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID       uuid.UUID
-	UserName string
-	FeedName string
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	FeedID     uuid.UUID
+	UserName   string
+	FeedName   string
+	FeedUrl    string
+	FolderName sql.NullString
+	Title      sql.NullString
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -111,7 +136,16 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.ID, &i.UserName, &i.FeedName); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.FeedID,
+			&i.UserName,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FolderName,
+			&i.Title,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -124,3 +158,117 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const getFollowedFeedsForUser = `-- name: GetFollowedFeedsForUser :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.link, feeds.description FROM feeds
+INNER JOIN feed_follows
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.name
+`
+
+func (q *Queries) GetFollowedFeedsForUser(ctx context.Context, userID uuid.UUID) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeedsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Link,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFeedFollows = `-- name: MoveFeedFollows :execrows
+UPDATE feed_follows SET feed_id = $1, updated_at = $2
+WHERE feed_id = $3
+AND user_id NOT IN (
+    SELECT user_id FROM feed_follows
+    WHERE feed_id = $1
+)
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows SET folder_id = $3, updated_at = $4
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowFolderParams struct {
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowTitle = `-- name: SetFeedFollowTitle :execrows
+UPDATE feed_follows SET title = $3, updated_at = $4
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowTitleParams struct {
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	Title     sql.NullString
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetFeedFollowTitle(ctx context.Context, arg SetFeedFollowTitleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowTitle,
+		arg.UserID,
+		arg.FeedID,
+		arg.Title,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -19,6 +19,17 @@ type Feed struct {
 	Url           string
 	UserID        uuid.UUID
 	LastFetchedAt sql.NullTime
+	Link          sql.NullString
+	Description   sql.NullString
+}
+
+type FeedHistory struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.UUID
+	Event     string
+	OldValue  sql.NullString
+	NewValue  sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,6 +38,23 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+}
+
+type FeedRetention struct {
+	FeedID    uuid.UUID
+	UpdatedAt time.Time
+	KeepDays  sql.NullInt32
+	KeepPosts sql.NullInt32
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
 }
 
 type Post struct {
@@ -38,11 +66,48 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
 }
 
-type User struct {
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+	HiddenAt  sql.NullTime
+}
+
+type PostTag struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+type Rule struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	Pattern   string
+	Regex     bool
+	Action    string
+	Tag       sql.NullString
+}
+
+type Session struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	IsAdmin      bool
 }
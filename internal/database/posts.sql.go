@@ -13,18 +13,92 @@ import (
 	"github.com/google/uuid"
 )
 
+const countPostsForFeed = `-- name: CountPostsForFeed :one
+SELECT count(*) FROM posts
+WHERE feed_id = $1
+`
+
+func (q *Queries) CountPostsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForFeed, feedID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPostsForUser = `-- name: CountPostsForUser :one
+SELECT count(*) FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+`
+
+func (q *Queries) CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteAllPosts = `-- name: DeleteAllPosts :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) DeleteAllPosts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAllPosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Categories,
+	)
+	return i, err
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, title, url, description, published_at, posts.feed_id, feed_follows.id, feed_follows.created_at, feed_follows.updated_at, user_id, feed_follows.feed_id FROM posts
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, COALESCE(feed_follows.title, feeds.name) AS feed_title FROM posts
 INNER JOIN feed_follows
 ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
+AND ($2 IS NULL OR feed_follows.folder_id = $2)
+AND (post_states.hidden_at IS NOT NULL) = $3
+AND ($4 IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = posts.id AND post_tags.user_id = feed_follows.user_id AND post_tags.tag = $4
+))
 ORDER BY posts.published_at DESC
-LIMIT $2
+LIMIT $5
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID   uuid.UUID
+	FolderID uuid.NullUUID
+	Hidden   bool
+	Tag      sql.NullString
+	Limit    int32
 }
 
 type GetPostsForUserRow struct {
@@ -36,15 +110,26 @@ type GetPostsForUserRow struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
 	ID_2        uuid.UUID
 	CreatedAt_2 time.Time
 	UpdatedAt_2 time.Time
 	UserID      uuid.UUID
 	FeedID_2    uuid.UUID
+	FolderID    uuid.NullUUID
+	Title_2     sql.NullString
+	FeedTitle   string
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.FolderID,
+		arg.Hidden,
+		arg.Tag,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -61,11 +146,58 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Author,
+			&i.Categories,
 			&i.ID_2,
 			&i.CreatedAt_2,
 			&i.UpdatedAt_2,
 			&i.UserID,
 			&i.FeedID_2,
+			&i.FolderID,
+			&i.Title_2,
+			&i.FeedTitle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsOfFollowedFeeds = `-- name: GetPostsOfFollowedFeeds :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.published_at
+`
+
+func (q *Queries) GetPostsOfFollowedFeeds(ctx context.Context, userID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsOfFollowedFeeds, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Categories,
 		); err != nil {
 			return nil, err
 		}
@@ -80,8 +212,99 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	return items, nil
 }
 
+const getPostsToPrune = `-- name: GetPostsToPrune :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories FROM posts
+WHERE posts.feed_id = $1
+AND (
+    posts.published_at < $2
+    OR (
+        SELECT COUNT(*) FROM posts AS newer
+        WHERE newer.feed_id = posts.feed_id AND newer.published_at > posts.published_at
+    ) >= $3
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id AND post_states.starred_at IS NOT NULL
+)
+AND NOT (
+    posts.created_at > $4
+    AND EXISTS (
+        SELECT 1 FROM feed_follows
+        LEFT JOIN post_states
+        ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+        WHERE feed_follows.feed_id = posts.feed_id AND post_states.read_at IS NULL
+    )
+)
+ORDER BY posts.published_at
+`
+
+type GetPostsToPruneParams struct {
+	FeedID          uuid.UUID
+	PublishedBefore sql.NullTime
+	KeepPosts       sql.NullInt64
+	UnreadSince     time.Time
+}
+
+func (q *Queries) GetPostsToPrune(ctx context.Context, arg GetPostsToPruneParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsToPrune,
+		arg.FeedID,
+		arg.PublishedBefore,
+		arg.KeepPosts,
+		arg.UnreadSince,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Categories,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePosts = `-- name: MovePosts :execrows
+UPDATE posts SET feed_id = $1, updated_at = $2
+WHERE feed_id = $3
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const postPost = `-- name: PostPost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
 VALUES (
     $1,
     $2,
@@ -90,8 +313,12 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
-)RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+    $8,
+    $9,
+    $10
+)
+ON CONFLICT (url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories
 `
 
 type PostPostParams struct {
@@ -103,6 +330,8 @@ type PostPostParams struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
 }
 
 func (q *Queries) PostPost(ctx context.Context, arg PostPostParams) (Post, error) {
@@ -115,6 +344,8 @@ func (q *Queries) PostPost(ctx context.Context, arg PostPostParams) (Post, error
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		arg.Categories,
 	)
 	var i Post
 	err := row.Scan(
@@ -126,6 +357,53 @@ func (q *Queries) PostPost(ctx context.Context, arg PostPostParams) (Post, error
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		&i.Categories,
 	)
 	return i, err
 }
+
+const prunePosts = `-- name: PrunePosts :execrows
+DELETE FROM posts
+WHERE posts.feed_id = $1
+AND (
+    posts.published_at < $2
+    OR (
+        SELECT COUNT(*) FROM posts AS newer
+        WHERE newer.feed_id = posts.feed_id AND newer.published_at > posts.published_at
+    ) >= $3
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id AND post_states.starred_at IS NOT NULL
+)
+AND NOT (
+    posts.created_at > $4
+    AND EXISTS (
+        SELECT 1 FROM feed_follows
+        LEFT JOIN post_states
+        ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+        WHERE feed_follows.feed_id = posts.feed_id AND post_states.read_at IS NULL
+    )
+)
+`
+
+type PrunePostsParams struct {
+	FeedID          uuid.UUID
+	PublishedBefore sql.NullTime
+	KeepPosts       sql.NullInt64
+	UnreadSince     time.Time
+}
+
+func (q *Queries) PrunePosts(ctx context.Context, arg PrunePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, prunePosts,
+		arg.FeedID,
+		arg.PublishedBefore,
+		arg.KeepPosts,
+		arg.UnreadSince,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,1220 @@
+// Package memory is an in-memory implementation of database.Querier for tests.
+//
+// It mirrors the constraints of the schema in sql/schema: unique names and urls,
+// one follow per user and feed, and deletes cascading from users to feeds, folders, follows,
+// posts, post states, tags, rules and sessions. Lookups that find nothing return sql.ErrNoRows like the real queries do.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+var ErrDuplicate = errors.New("duplicate key value violates unique constraint")
+
+type Queries struct {
+	mu sync.Mutex
+	tables
+}
+
+// tables holds the rows of every table
+type tables struct {
+	users     []database.User
+	feeds     []database.Feed
+	follows   []database.FeedFollow
+	folders   []database.Folder
+	posts     []database.Post
+	history   []database.FeedHistory
+	retention []database.FeedRetention
+	states    []database.PostState
+	tags      []database.PostTag
+	rules     []database.Rule
+	sessions  []database.Session
+}
+
+// clone copies every table, so that later changes to the rows leave the copy untouched
+func (t tables) clone() tables {
+	return tables{
+		users:     slices.Clone(t.users),
+		feeds:     slices.Clone(t.feeds),
+		follows:   slices.Clone(t.follows),
+		folders:   slices.Clone(t.folders),
+		posts:     slices.Clone(t.posts),
+		history:   slices.Clone(t.history),
+		retention: slices.Clone(t.retention),
+		states:    slices.Clone(t.states),
+		tags:      slices.Clone(t.tags),
+		rules:     slices.Clone(t.rules),
+		sessions:  slices.Clone(t.sessions),
+	}
+}
+
+var _ database.Querier = (*Queries)(nil)
+
+func New() *Queries {
+	return &Queries{}
+}
+
+// Users
+
+func (q *Queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, u := range q.users {
+		if u.ID == arg.ID || u.Name == arg.Name {
+			return database.User{}, ErrDuplicate
+		}
+	}
+	user := database.User{
+		ID:           arg.ID,
+		CreatedAt:    arg.CreatedAt,
+		UpdatedAt:    arg.UpdatedAt,
+		Name:         arg.Name,
+		PasswordHash: arg.PasswordHash,
+		IsAdmin:      arg.IsAdmin,
+	}
+	q.users = append(q.users, user)
+	return user, nil
+}
+
+func (q *Queries) GetUser(ctx context.Context, name string) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, u := range q.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.userByID(id)
+}
+
+func (q *Queries) userByID(id uuid.UUID) (database.User, error) {
+	for _, u := range q.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetUsers(ctx context.Context) ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var names []string
+	for _, u := range q.users {
+		names = append(names, u.Name)
+	}
+	return names, nil
+}
+
+func (q *Queries) ResetUsers(ctx context.Context) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := int64(len(q.users))
+	q.users = nil
+	q.feeds = nil
+	q.follows = nil
+	q.folders = nil
+	q.posts = nil
+	q.history = nil
+	q.retention = nil
+	q.states = nil
+	q.tags = nil
+	q.rules = nil
+	q.sessions = nil
+	return n, nil
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, u := range q.users {
+		if u.ID == arg.ID {
+			q.users[i].PasswordHash = arg.PasswordHash
+			q.users[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *Queries) GetAdmins(ctx context.Context) ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var names []string
+	for _, u := range q.users {
+		if u.IsAdmin {
+			names = append(names, u.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, u := range q.users {
+		if u.ID == arg.ID {
+			q.users[i].IsAdmin = arg.IsAdmin
+			q.users[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *Queries) UpdateUserName(ctx context.Context, arg database.UpdateUserNameParams) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, u := range q.users {
+		if u.Name == arg.Name && u.ID != arg.ID {
+			return database.User{}, ErrDuplicate
+		}
+	}
+	for i, u := range q.users {
+		if u.ID == arg.ID {
+			q.users[i].Name = arg.Name
+			q.users[i].UpdatedAt = arg.UpdatedAt
+			return q.users[i], nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetUserStats(ctx context.Context, userID uuid.UUID) (database.GetUserStatsRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := database.GetUserStatsRow{}
+	created := make(map[uuid.UUID]bool)
+	for _, f := range q.feeds {
+		if f.UserID == userID {
+			created[f.ID] = true
+			stats.FeedsCreated++
+		}
+	}
+	followed := make(map[uuid.UUID]bool)
+	for _, f := range q.follows {
+		if f.UserID == userID {
+			followed[f.FeedID] = true
+			stats.Follows++
+		} else if created[f.FeedID] {
+			stats.OthersFollowsOfFeedsCreated++
+		}
+	}
+	read := make(map[uuid.UUID]bool)
+	hidden := make(map[uuid.UUID]bool)
+	for _, st := range q.states {
+		if st.UserID == userID {
+			stats.PostStates++
+			read[st.PostID] = st.ReadAt.Valid
+			hidden[st.PostID] = st.HiddenAt.Valid
+		}
+	}
+	for _, p := range q.posts {
+		if followed[p.FeedID] && !read[p.ID] && !hidden[p.ID] {
+			stats.Unread++
+		}
+		if created[p.FeedID] {
+			stats.PostsOfFeedsCreated++
+		}
+	}
+	return stats, nil
+}
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.users)
+	q.users = filter(q.users, func(u database.User) bool { return u.ID != id })
+	if n == len(q.users) {
+		return 0, nil
+	}
+	q.deleteFeeds(func(f database.Feed) bool { return f.UserID == id })
+	q.follows = filter(q.follows, func(f database.FeedFollow) bool { return f.UserID != id })
+	q.folders = filter(q.folders, func(f database.Folder) bool { return f.UserID != id })
+	q.states = filter(q.states, func(st database.PostState) bool { return st.UserID != id })
+	q.tags = filter(q.tags, func(t database.PostTag) bool { return t.UserID != id })
+	q.rules = filter(q.rules, func(r database.Rule) bool { return r.UserID != id })
+	q.sessions = filter(q.sessions, func(session database.Session) bool { return session.UserID != id })
+	return 1, nil
+}
+
+// Sessions
+
+func (q *Queries) CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.userByID(arg.UserID); err != nil {
+		return database.Session{}, errors.New("session references a user that does not exist")
+	}
+	for _, session := range q.sessions {
+		if session.TokenHash == arg.TokenHash {
+			return database.Session{}, ErrDuplicate
+		}
+	}
+	session := database.Session(arg)
+	q.sessions = append(q.sessions, session)
+	return session, nil
+}
+
+func (q *Queries) GetSession(ctx context.Context, tokenHash string) (database.Session, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, session := range q.sessions {
+		if session.TokenHash == tokenHash {
+			return session, nil
+		}
+	}
+	return database.Session{}, sql.ErrNoRows
+}
+
+func (q *Queries) DeleteSession(ctx context.Context, tokenHash string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.sessions = filter(q.sessions, func(session database.Session) bool { return session.TokenHash != tokenHash })
+	return nil
+}
+
+func (q *Queries) DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.sessions)
+	q.sessions = filter(q.sessions, func(session database.Session) bool { return session.UserID != userID })
+	return int64(n - len(q.sessions)), nil
+}
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context, expiresAt time.Time) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.sessions)
+	q.sessions = filter(q.sessions, func(session database.Session) bool { return session.ExpiresAt.After(expiresAt) })
+	return int64(n - len(q.sessions)), nil
+}
+
+// Feeds
+
+func (q *Queries) PostFeed(ctx context.Context, arg database.PostFeedParams) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, f := range q.feeds {
+		if f.ID == arg.ID || f.Url == arg.Url {
+			return database.Feed{}, ErrDuplicate
+		}
+	}
+	if _, err := q.userByID(arg.UserID); err != nil {
+		return database.Feed{}, errors.New("feed references a user that does not exist")
+	}
+	feed := database.Feed{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Name:        arg.Name,
+		Url:         arg.Url,
+		UserID:      arg.UserID,
+		Link:        arg.Link,
+		Description: arg.Description,
+	}
+	q.feeds = append(q.feeds, feed)
+	return feed, nil
+}
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]database.Feed(nil), q.feeds...), nil
+}
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, f := range q.feeds {
+		if f.Url == url {
+			return f, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (q *Queries) feedIndex(id uuid.UUID) int {
+	for i, f := range q.feeds {
+		if f.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Queries) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.feeds) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	next := q.feeds[0]
+	for _, f := range q.feeds[1:] {
+		if !next.LastFetchedAt.Valid {
+			break
+		}
+		if !f.LastFetchedAt.Valid || f.LastFetchedAt.Time.Before(next.LastFetchedAt.Time) {
+			next = f
+		}
+	}
+	return next, nil
+}
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if i := q.feedIndex(arg.ID); i >= 0 {
+		q.feeds[i].LastFetchedAt = arg.LastFetchedAt
+		q.feeds[i].UpdatedAt = arg.UpdatedAt
+	}
+	return nil
+}
+
+func (q *Queries) UpdateFeedName(ctx context.Context, arg database.UpdateFeedNameParams) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := q.feedIndex(arg.ID)
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	q.feeds[i].Name = arg.Name
+	q.feeds[i].UpdatedAt = arg.UpdatedAt
+	return q.feeds[i], nil
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg database.UpdateFeedURLParams) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := q.feedIndex(arg.ID)
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	for _, f := range q.feeds {
+		if f.Url == arg.Url && f.ID != arg.ID {
+			return database.Feed{}, ErrDuplicate
+		}
+	}
+	q.feeds[i].Url = arg.Url
+	q.feeds[i].UpdatedAt = arg.UpdatedAt
+	return q.feeds[i], nil
+}
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deleteFeeds(func(f database.Feed) bool { return f.ID == id })
+	return nil
+}
+
+// deleteFeeds removes the feeds matching del along with their follows, posts and history
+func (q *Queries) deleteFeeds(del func(database.Feed) bool) {
+	deleted := make(map[uuid.UUID]bool)
+	feeds := q.feeds[:0]
+	for _, f := range q.feeds {
+		if del(f) {
+			deleted[f.ID] = true
+		} else {
+			feeds = append(feeds, f)
+		}
+	}
+	q.feeds = feeds
+	q.follows = filter(q.follows, func(f database.FeedFollow) bool { return !deleted[f.FeedID] })
+	q.posts = filter(q.posts, func(p database.Post) bool { return !deleted[p.FeedID] })
+	q.history = filter(q.history, func(h database.FeedHistory) bool { return !deleted[h.FeedID] })
+	q.retention = filter(q.retention, func(r database.FeedRetention) bool { return !deleted[r.FeedID] })
+	q.rules = filter(q.rules, func(r database.Rule) bool { return !r.FeedID.Valid || !deleted[r.FeedID.UUID] })
+	q.dropOrphanStates()
+}
+
+// dropOrphanStates removes the post states and tags whose post no longer exists
+func (q *Queries) dropOrphanStates() {
+	posts := make(map[uuid.UUID]bool)
+	for _, p := range q.posts {
+		posts[p.ID] = true
+	}
+	q.states = filter(q.states, func(st database.PostState) bool { return posts[st.PostID] })
+	q.tags = filter(q.tags, func(t database.PostTag) bool { return posts[t.PostID] })
+}
+
+// Feed retention
+
+func (q *Queries) GetFeedRetention(ctx context.Context, feedID uuid.UUID) (database.FeedRetention, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, r := range q.retention {
+		if r.FeedID == feedID {
+			return r, nil
+		}
+	}
+	return database.FeedRetention{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetFeedRetentions(ctx context.Context) ([]database.FeedRetention, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]database.FeedRetention(nil), q.retention...), nil
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.feedIndex(arg.FeedID) < 0 {
+		return errors.New("feed retention references a feed that does not exist")
+	}
+	q.retention = filter(q.retention, func(r database.FeedRetention) bool { return r.FeedID != arg.FeedID })
+	q.retention = append(q.retention, database.FeedRetention(arg))
+	return nil
+}
+
+func (q *Queries) DeleteFeedRetention(ctx context.Context, feedID uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.retention = filter(q.retention, func(r database.FeedRetention) bool { return r.FeedID != feedID })
+	return nil
+}
+
+// Feed history
+
+func (q *Queries) CreateFeedHistory(ctx context.Context, arg database.CreateFeedHistoryParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.history = append(q.history, database.FeedHistory(arg))
+	return nil
+}
+
+func (q *Queries) GetFeedHistory(ctx context.Context, feedID uuid.UUID) ([]database.FeedHistory, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	history := filter(q.history, func(h database.FeedHistory) bool { return h.FeedID == feedID })
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].CreatedAt.Before(history[j].CreatedAt)
+	})
+	return history, nil
+}
+
+func (q *Queries) MoveFeedHistory(ctx context.Context, arg database.MoveFeedHistoryParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.history {
+		if q.history[i].FeedID == arg.FromFeedID {
+			q.history[i].FeedID = arg.ToFeedID
+		}
+	}
+	return nil
+}
+
+// Follows
+
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, f := range q.follows {
+		if f.ID == arg.ID || (f.UserID == arg.UserID && f.FeedID == arg.FeedID) {
+			return database.CreateFeedFollowRow{}, ErrDuplicate
+		}
+	}
+	user, err := q.userByID(arg.UserID)
+	if err != nil {
+		return database.CreateFeedFollowRow{}, errors.New("follow references a user that does not exist")
+	}
+	i := q.feedIndex(arg.FeedID)
+	if i < 0 {
+		return database.CreateFeedFollowRow{}, errors.New("follow references a feed that does not exist")
+	}
+	q.follows = append(q.follows, database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	})
+	return database.CreateFeedFollowRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		FeedName:  q.feeds[i].Name,
+		UserName:  user.Name,
+	}, nil
+}
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.follows = filter(q.follows, func(f database.FeedFollow) bool {
+		return f.UserID != arg.UserID || f.FeedID != arg.FeedID
+	})
+	return nil
+}
+
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	user, err := q.userByID(userID)
+	if err != nil {
+		return nil, nil
+	}
+	var rows []database.GetFeedFollowsForUserRow
+	for _, f := range q.follows {
+		if f.UserID != userID {
+			continue
+		}
+		feed := q.feeds[q.feedIndex(f.FeedID)]
+		row := database.GetFeedFollowsForUserRow{
+			ID:        f.ID,
+			CreatedAt: f.CreatedAt,
+			FeedID:    f.FeedID,
+			UserName:  user.Name,
+			FeedName:  feed.Name,
+			FeedUrl:   feed.Url,
+			Title:     f.Title,
+		}
+		for _, folder := range q.folders {
+			if f.FolderID.Valid && folder.ID == f.FolderID.UUID {
+				row.FolderName = sql.NullString{String: folder.Name, Valid: true}
+			}
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return cmp.Or(rows[i].Title.String, rows[i].FeedName) < cmp.Or(rows[j].Title.String, rows[j].FeedName)
+	})
+	return rows, nil
+}
+
+func (q *Queries) GetFollowedFeedsForUser(ctx context.Context, userID uuid.UUID) ([]database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var feeds []database.Feed
+	for _, f := range q.follows {
+		if f.UserID == userID {
+			feeds = append(feeds, q.feeds[q.feedIndex(f.FeedID)])
+		}
+	}
+	sort.SliceStable(feeds, func(i, j int) bool {
+		return feeds[i].Name < feeds[j].Name
+	})
+	return feeds, nil
+}
+
+func (q *Queries) CountFollowsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int64(len(filter(q.follows, func(f database.FeedFollow) bool { return f.FeedID == feedID }))), nil
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	following := make(map[uuid.UUID]bool)
+	for _, f := range q.follows {
+		if f.FeedID == arg.ToFeedID {
+			following[f.UserID] = true
+		}
+	}
+	moved := int64(0)
+	for i, f := range q.follows {
+		if f.FeedID == arg.FromFeedID && !following[f.UserID] {
+			q.follows[i].FeedID = arg.ToFeedID
+			q.follows[i].UpdatedAt = arg.UpdatedAt
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if arg.FolderID.Valid && q.folderIndex(arg.FolderID.UUID) < 0 {
+		return 0, errors.New("follow references a folder that does not exist")
+	}
+	updated := int64(0)
+	for i, f := range q.follows {
+		if f.UserID == arg.UserID && f.FeedID == arg.FeedID {
+			q.follows[i].FolderID = arg.FolderID
+			q.follows[i].UpdatedAt = arg.UpdatedAt
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (q *Queries) SetFeedFollowTitle(ctx context.Context, arg database.SetFeedFollowTitleParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	updated := int64(0)
+	for i, f := range q.follows {
+		if f.UserID == arg.UserID && f.FeedID == arg.FeedID {
+			q.follows[i].Title = arg.Title
+			q.follows[i].UpdatedAt = arg.UpdatedAt
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// Folders
+
+func (q *Queries) folderIndex(id uuid.UUID) int {
+	for i, f := range q.folders {
+		if f.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Queries) CreateFolder(ctx context.Context, arg database.CreateFolderParams) (database.Folder, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, f := range q.folders {
+		if f.ID == arg.ID || (f.UserID == arg.UserID && f.Name == arg.Name) {
+			return database.Folder{}, ErrDuplicate
+		}
+	}
+	if _, err := q.userByID(arg.UserID); err != nil {
+		return database.Folder{}, errors.New("folder references a user that does not exist")
+	}
+	folder := database.Folder(arg)
+	q.folders = append(q.folders, folder)
+	return folder, nil
+}
+
+func (q *Queries) GetFolder(ctx context.Context, arg database.GetFolderParams) (database.Folder, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, f := range q.folders {
+		if f.UserID == arg.UserID && f.Name == arg.Name {
+			return f, nil
+		}
+	}
+	return database.Folder{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]database.Folder, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	folders := filter(q.folders, func(f database.Folder) bool { return f.UserID == userID })
+	sort.SliceStable(folders, func(i, j int) bool {
+		return folders[i].Name < folders[j].Name
+	})
+	return folders, nil
+}
+
+func (q *Queries) RenameFolder(ctx context.Context, arg database.RenameFolderParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := q.folderIndex(arg.ID)
+	if i < 0 {
+		return nil
+	}
+	for _, f := range q.folders {
+		if f.ID != arg.ID && f.UserID == q.folders[i].UserID && f.Name == arg.Name {
+			return ErrDuplicate
+		}
+	}
+	q.folders[i].Name = arg.Name
+	q.folders[i].UpdatedAt = arg.UpdatedAt
+	return nil
+}
+
+// DeleteFolder leaves the folder's follows unfiled, like the schema's ON DELETE SET NULL
+func (q *Queries) DeleteFolder(ctx context.Context, id uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.folders = filter(q.folders, func(f database.Folder) bool { return f.ID != id })
+	for i, f := range q.follows {
+		if f.FolderID.Valid && f.FolderID.UUID == id {
+			q.follows[i].FolderID = uuid.NullUUID{}
+		}
+	}
+	return nil
+}
+
+// Posts
+
+func (q *Queries) PostPost(ctx context.Context, arg database.PostPostParams) (database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, p := range q.posts {
+		if p.Url == arg.Url {
+			// ON CONFLICT (url) DO NOTHING returns no row
+			return database.Post{}, sql.ErrNoRows
+		}
+		if p.ID == arg.ID {
+			return database.Post{}, ErrDuplicate
+		}
+	}
+	if q.feedIndex(arg.FeedID) < 0 {
+		return database.Post{}, errors.New("post references a feed that does not exist")
+	}
+	post := database.Post(arg)
+	q.posts = append(q.posts, post)
+	return post, nil
+}
+
+func (q *Queries) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var rows []database.GetPostsForUserRow
+	for _, f := range q.follows {
+		if f.UserID != arg.UserID || (arg.FolderID.Valid && f.FolderID != arg.FolderID) {
+			continue
+		}
+		for _, p := range q.posts {
+			if p.FeedID != f.FeedID || q.hidden(f.UserID, p.ID) != arg.Hidden {
+				continue
+			}
+			if arg.Tag.Valid && !q.tagged(f.UserID, p.ID, arg.Tag.String) {
+				continue
+			}
+			rows = append(rows, database.GetPostsForUserRow{
+				ID:          p.ID,
+				CreatedAt:   p.CreatedAt,
+				UpdatedAt:   p.UpdatedAt,
+				Title:       p.Title,
+				Url:         p.Url,
+				Description: p.Description,
+				PublishedAt: p.PublishedAt,
+				FeedID:      p.FeedID,
+				Author:      p.Author,
+				Categories:  p.Categories,
+				ID_2:        f.ID,
+				CreatedAt_2: f.CreatedAt,
+				UpdatedAt_2: f.UpdatedAt,
+				UserID:      f.UserID,
+				FeedID_2:    f.FeedID,
+				FolderID:    f.FolderID,
+				Title_2:     f.Title,
+				FeedTitle:   cmp.Or(f.Title.String, q.feeds[q.feedIndex(f.FeedID)].Name),
+			})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].PublishedAt.After(rows[j].PublishedAt)
+	})
+	if int(arg.Limit) < len(rows) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+func (q *Queries) CountPostsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int64(len(filter(q.posts, func(p database.Post) bool { return p.FeedID == feedID }))), nil
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg database.MovePostsParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	moved := int64(0)
+	for i, p := range q.posts {
+		if p.FeedID == arg.FromFeedID {
+			q.posts[i].FeedID = arg.ToFeedID
+			q.posts[i].UpdatedAt = arg.UpdatedAt
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (q *Queries) DeleteAllPosts(ctx context.Context) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := int64(len(q.posts))
+	q.posts = nil
+	q.states = nil
+	q.tags = nil
+	return n, nil
+}
+
+func (q *Queries) GetPostsToPrune(ctx context.Context, arg database.GetPostsToPruneParams) ([]database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.postsToPrune(arg), nil
+}
+
+// postsToPrune returns the posts of a feed past the retention described by arg, oldest first
+func (q *Queries) postsToPrune(arg database.GetPostsToPruneParams) []database.Post {
+	posts := filter(q.posts, func(p database.Post) bool { return p.FeedID == arg.FeedID })
+	var prune []database.Post
+	for _, p := range posts {
+		newer := int64(0)
+		for _, other := range posts {
+			if other.PublishedAt.After(p.PublishedAt) {
+				newer++
+			}
+		}
+		old := arg.PublishedBefore.Valid && p.PublishedAt.Before(arg.PublishedBefore.Time)
+		if !old && !(arg.KeepPosts.Valid && newer >= arg.KeepPosts.Int64) {
+			continue
+		}
+		starred := false
+		read := make(map[uuid.UUID]bool)
+		for _, st := range q.states {
+			if st.PostID == p.ID {
+				starred = starred || st.StarredAt.Valid
+				read[st.UserID] = st.ReadAt.Valid
+			}
+		}
+		unread := false
+		for _, f := range q.follows {
+			unread = unread || (f.FeedID == p.FeedID && !read[f.UserID])
+		}
+		if starred || (unread && p.CreatedAt.After(arg.UnreadSince)) {
+			continue
+		}
+		prune = append(prune, p)
+	}
+	sort.SliceStable(prune, func(i, j int) bool {
+		return prune[i].PublishedAt.Before(prune[j].PublishedAt)
+	})
+	return prune
+}
+
+func (q *Queries) PrunePosts(ctx context.Context, arg database.PrunePostsParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	prune := make(map[uuid.UUID]bool)
+	for _, p := range q.postsToPrune(database.GetPostsToPruneParams(arg)) {
+		prune[p.ID] = true
+	}
+	q.posts = filter(q.posts, func(p database.Post) bool { return !prune[p.ID] })
+	q.dropOrphanStates()
+	return int64(len(prune)), nil
+}
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, p := range q.posts {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetPostsOfFollowedFeeds(ctx context.Context, userID uuid.UUID) ([]database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var posts []database.Post
+	for _, f := range q.follows {
+		if f.UserID != userID {
+			continue
+		}
+		posts = append(posts, filter(q.posts, func(p database.Post) bool { return p.FeedID == f.FeedID })...)
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].PublishedAt.Before(posts[j].PublishedAt)
+	})
+	return posts, nil
+}
+
+func (q *Queries) CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	count := int64(0)
+	for _, f := range q.follows {
+		if f.UserID != userID {
+			continue
+		}
+		for _, p := range q.posts {
+			if p.FeedID == f.FeedID {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// Post states
+
+// state returns the index of userID's state for postID, creating it if needed
+func (q *Queries) state(userID, postID uuid.UUID) (int, error) {
+	for i, st := range q.states {
+		if st.UserID == userID && st.PostID == postID {
+			return i, nil
+		}
+	}
+	if _, err := q.userByID(userID); err != nil {
+		return -1, errors.New("post state references a user that does not exist")
+	}
+	found := false
+	for _, p := range q.posts {
+		found = found || p.ID == postID
+	}
+	if !found {
+		return -1, errors.New("post state references a post that does not exist")
+	}
+	q.states = append(q.states, database.PostState{UserID: userID, PostID: postID})
+	return len(q.states) - 1, nil
+}
+
+func (q *Queries) SetPostRead(ctx context.Context, arg database.SetPostReadParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i, err := q.state(arg.UserID, arg.PostID)
+	if err != nil {
+		return err
+	}
+	q.states[i].ReadAt = arg.ReadAt
+	return nil
+}
+
+func (q *Queries) SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i, err := q.state(arg.UserID, arg.PostID)
+	if err != nil {
+		return err
+	}
+	q.states[i].StarredAt = arg.StarredAt
+	return nil
+}
+
+func (q *Queries) SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i, err := q.state(arg.UserID, arg.PostID)
+	if err != nil {
+		return err
+	}
+	q.states[i].HiddenAt = arg.HiddenAt
+	return nil
+}
+
+// hidden reports whether userID hid postID
+func (q *Queries) hidden(userID, postID uuid.UUID) bool {
+	for _, st := range q.states {
+		if st.UserID == userID && st.PostID == postID {
+			return st.HiddenAt.Valid
+		}
+	}
+	return false
+}
+
+func (q *Queries) GetPostEntries(ctx context.Context, arg database.GetPostEntriesParams) ([]database.GetPostEntriesRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var rows []database.GetPostEntriesRow
+	for _, f := range q.follows {
+		if f.UserID != arg.UserID {
+			continue
+		}
+		feed := q.feeds[q.feedIndex(f.FeedID)]
+		for _, p := range q.posts {
+			if p.FeedID != f.FeedID || q.hidden(f.UserID, p.ID) {
+				continue
+			}
+			row := database.GetPostEntriesRow{
+				ID:          p.ID,
+				Title:       p.Title,
+				Url:         p.Url,
+				Description: p.Description,
+				PublishedAt: p.PublishedAt,
+				FeedID:      p.FeedID,
+				FeedName:    cmp.Or(f.Title.String, feed.Name),
+			}
+			for _, st := range q.states {
+				if st.UserID == f.UserID && st.PostID == p.ID {
+					row.ReadAt = st.ReadAt
+					row.StarredAt = st.StarredAt
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].PublishedAt.After(rows[j].PublishedAt)
+	})
+	if int(arg.Limit) < len(rows) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+// Post tags
+
+func (q *Queries) TagPost(ctx context.Context, arg database.TagPostParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.tagged(arg.UserID, arg.PostID, arg.Tag) {
+		return nil
+	}
+	if _, err := q.userByID(arg.UserID); err != nil {
+		return errors.New("post tag references a user that does not exist")
+	}
+	if len(filter(q.posts, func(p database.Post) bool { return p.ID == arg.PostID })) == 0 {
+		return errors.New("post tag references a post that does not exist")
+	}
+	q.tags = append(q.tags, database.PostTag(arg))
+	return nil
+}
+
+// tagged reports whether userID tagged postID with tag
+func (q *Queries) tagged(userID, postID uuid.UUID, tag string) bool {
+	for _, t := range q.tags {
+		if t.UserID == userID && t.PostID == postID && t.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Queries) GetPostTags(ctx context.Context, arg database.GetPostTagsParams) ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var tags []string
+	for _, t := range q.tags {
+		if t.UserID == arg.UserID && t.PostID == arg.PostID {
+			tags = append(tags, t.Tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func (q *Queries) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	seen := make(map[string]bool)
+	var tags []string
+	for _, t := range q.tags {
+		if t.UserID == userID && !seen[t.Tag] {
+			seen[t.Tag] = true
+			tags = append(tags, t.Tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Rules
+
+func (q *Queries) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.userByID(arg.UserID); err != nil {
+		return database.Rule{}, errors.New("rule references a user that does not exist")
+	}
+	if arg.FeedID.Valid && q.feedIndex(arg.FeedID.UUID) < 0 {
+		return database.Rule{}, errors.New("rule references a feed that does not exist")
+	}
+	rule := database.Rule(arg)
+	q.rules = append(q.rules, rule)
+	return rule, nil
+}
+
+func (q *Queries) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetRulesForUserRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var rows []database.GetRulesForUserRow
+	for _, r := range q.rules {
+		if r.UserID != userID {
+			continue
+		}
+		row := database.GetRulesForUserRow{
+			ID:        r.ID,
+			CreatedAt: r.CreatedAt,
+			UserID:    r.UserID,
+			FeedID:    r.FeedID,
+			Field:     r.Field,
+			Pattern:   r.Pattern,
+			Regex:     r.Regex,
+			Action:    r.Action,
+			Tag:       r.Tag,
+		}
+		if r.FeedID.Valid {
+			row.FeedUrl = sql.NullString{String: q.feeds[q.feedIndex(r.FeedID.UUID)].Url, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].CreatedAt.Before(rows[j].CreatedAt)
+	})
+	return rows, nil
+}
+
+func (q *Queries) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Rule, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	followers := make(map[uuid.UUID]bool)
+	for _, f := range q.follows {
+		if f.FeedID == feedID {
+			followers[f.UserID] = true
+		}
+	}
+	rules := filter(q.rules, func(r database.Rule) bool {
+		return followers[r.UserID] && (!r.FeedID.Valid || r.FeedID.UUID == feedID)
+	})
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+	return rules, nil
+}
+
+func (q *Queries) DeleteRule(ctx context.Context, arg database.DeleteRuleParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.rules)
+	q.rules = filter(q.rules, func(r database.Rule) bool { return r.ID != arg.ID || r.UserID != arg.UserID })
+	return int64(n - len(q.rules)), nil
+}
+
+func (q *Queries) MoveRules(ctx context.Context, arg database.MoveRulesParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, r := range q.rules {
+		if r.FeedID.Valid && r.FeedID == arg.FromFeedID {
+			q.rules[i].FeedID = arg.ToFeedID
+		}
+	}
+	return nil
+}
+
+// Transactions
+
+// InTx runs fn, undoing every change it made when it returns an error, like a rolled back transaction.
+// Unlike a real transaction it does not isolate fn from queries made at the same time elsewhere.
+func (q *Queries) InTx(ctx context.Context, fn func() error) error {
+	q.mu.Lock()
+	saved := q.tables.clone()
+	q.mu.Unlock()
+	err := fn()
+	if err != nil {
+		q.mu.Lock()
+		q.tables = saved
+		q.mu.Unlock()
+	}
+	return err
+}
+
+func filter[T any](items []T, keep func(T) bool) []T {
+	var kept []T
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
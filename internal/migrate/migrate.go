@@ -0,0 +1,342 @@
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versionTable is the table goose keeps its bookkeeping in.
+// Sharing it lets databases migrated with the goose cli be upgraded by gator and vice versa.
+const versionTable = "goose_db_version"
+
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	SQLite
+)
+
+// createVersionTable holds each dialect's version of goose's bookkeeping table
+var createVersionTable = map[Dialect]string{
+	Postgres: `CREATE TABLE IF NOT EXISTS ` + versionTable + ` (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP DEFAULT now()
+)`,
+	SQLite: `CREATE TABLE IF NOT EXISTS ` + versionTable + ` (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version_id INTEGER NOT NULL,
+    is_applied INTEGER NOT NULL,
+    tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`,
+}
+
+// tableExists holds each dialect's query telling whether the table named by its parameter exists
+var tableExists = map[Dialect]string{
+	Postgres: `SELECT to_regclass($1::text) IS NOT NULL`,
+	SQLite:   `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)`,
+}
+
+// Migrator applies migrations to a database of the given dialect
+type Migrator struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+func New(db *sql.DB, dialect Dialect) *Migrator {
+	return &Migrator{
+		db:      db,
+		dialect: dialect,
+	}
+}
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load reads every '<version>_<name>.sql' file in dir, split into its
+// '-- +goose Up' and '-- +goose Down' sections, ordered by version.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	migrations := []Migration{}
+	for _, file := range files {
+		base := path.Base(file)
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("error: migration '%v' is not named '<version>_<name>.sql'", base)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error: migration '%v' has no numeric version \n%v", base, err)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("error: cannot read migration '%v' \n%v", base, err)
+		}
+		up, down := split(string(data))
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    name,
+			Up:      up,
+			Down:    down,
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("error: duplicate migration version %v", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+func split(data string) (string, string) {
+	var up, down strings.Builder
+	var section *strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			section = &up
+			continue
+		case "-- +goose Down":
+			section = &down
+			continue
+		}
+		if section != nil {
+			section.WriteString(line)
+			section.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(up.String()), strings.TrimSpace(down.String())
+}
+
+func (m *Migrator) ensureVersionTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, createVersionTable[m.dialect])
+	if err != nil {
+		return fmt.Errorf("error: cannot create %v table \n%v", versionTable, err)
+	}
+	return nil
+}
+
+// applied returns when each applied version was applied.
+// It only reads: a database without the bookkeeping table has no version applied.
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	missing, err := m.MissingTables(ctx, []string{versionTable})
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return map[int64]time.Time{}, nil
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version_id, is_applied, tstamp FROM `+versionTable+` ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("error: cannot read %v table \n%v", versionTable, err)
+	}
+	defer rows.Close()
+	seen := make(map[int64]bool)
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var tstamp sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &tstamp); err != nil {
+			return nil, err
+		}
+		// Only the latest row for a version counts
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if isApplied && version > 0 {
+			versions[version] = tstamp.Time
+		}
+	}
+	return versions, rows.Err()
+}
+
+// MissingTables returns the tables of the list that do not exist in the database
+func (m *Migrator) MissingTables(ctx context.Context, tables []string) ([]string, error) {
+	missing := []string{}
+	for _, table := range tables {
+		var exists bool
+		err := m.db.QueryRowContext(ctx, tableExists[m.dialect], table).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("error: cannot look up %v table \n%v", table, err)
+		}
+		if !exists {
+			missing = append(missing, table)
+		}
+	}
+	return missing, nil
+}
+
+// Current returns the highest applied version, 0 for an empty database
+func (m *Migrator) Current(ctx context.Context) (int64, error) {
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	current := int64(0)
+	for v := range versions {
+		if v > current {
+			current = v
+		}
+	}
+	return current, nil
+}
+
+// Pending returns the migrations not applied to the database, in order.
+// Unlike comparing Current with Latest, this catches a version skipped below the highest applied one.
+func (m *Migrator) Pending(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, mig := range migrations {
+		if _, ok := versions[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Latest returns the version the database is at once every migration is applied
+func Latest(migrations []Migration) int64 {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func (m *Migrator) Statuses(ctx context.Context, migrations []Migration) ([]Status, error) {
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(migrations))
+	for _, mig := range migrations {
+		at, ok := versions[mig.Version]
+		statuses = append(statuses, Status{
+			Migration: mig,
+			Applied:   ok,
+			AppliedAt: at,
+		})
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+// It is the only step creating the bookkeeping table.
+func (m *Migrator) Up(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	err := m.ensureVersionTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := m.Pending(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for _, mig := range pending {
+		err := m.run(ctx, mig.Up, `INSERT INTO `+versionTable+` (version_id, is_applied) VALUES ($1, true)`, mig.Version)
+		if err != nil {
+			return done, fmt.Errorf("error: migration %v_%v failed \n%v", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Baseline records the pending migrations up to version as applied without running them, for a
+// database whose schema was created by other means. It returns the migrations recorded.
+func (m *Migrator) Baseline(ctx context.Context, migrations []Migration, version int64) ([]Migration, error) {
+	err := m.ensureVersionTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := m.Pending(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+	done := []Migration{}
+	for _, mig := range pending {
+		if mig.Version > version {
+			break
+		}
+		err := m.run(ctx, "", `INSERT INTO `+versionTable+` (version_id, is_applied) VALUES ($1, true)`, mig.Version)
+		if err != nil {
+			return done, fmt.Errorf("error: recording migration %v_%v failed \n%v", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down rolls back the most recently applied migration and returns it, or nil if none is applied
+func (m *Migrator) Down(ctx context.Context, migrations []Migration) (*Migration, error) {
+	current, err := m.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current == 0 {
+		return nil, nil
+	}
+	for i := range migrations {
+		mig := migrations[i]
+		if mig.Version != current {
+			continue
+		}
+		err := m.run(ctx, mig.Down, `DELETE FROM `+versionTable+` WHERE version_id = $1`, mig.Version)
+		if err != nil {
+			return nil, fmt.Errorf("error: rolling back migration %v_%v failed \n%v", mig.Version, mig.Name, err)
+		}
+		return &mig, nil
+	}
+	return nil, fmt.Errorf("error: database is at version %v which this gator does not know how to roll back", current)
+}
+
+// run executes a migration's statements and its bookkeeping in one transaction
+func (m *Migrator) run(ctx context.Context, statements, record string, version int64) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if statements != "" {
+		_, err = tx.ExecContext(ctx, statements)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, record, version)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,228 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+var testMigrations = fstest.MapFS{
+	"schema/001_users.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE users (id INTEGER PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE users;
+`)},
+	"schema/002_feeds.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE feeds (id INTEGER PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE feeds;
+`)},
+	"schema/010_posts.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE posts (id INTEGER PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE posts;
+`)},
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func versionsOf(migrations []Migration) []int64 {
+	versions := make([]int64, len(migrations))
+	for i, m := range migrations {
+		versions[i] = m.Version
+	}
+	return versions
+}
+
+func hasTable(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = $1`, name).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n == 1
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testMigrations, "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versionsOf(migrations); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 10 {
+		t.Fatalf("got versions %v, want [1 2 10]", got)
+	}
+	if m := migrations[0]; m.Name != "users" || m.Up != "CREATE TABLE users (id INTEGER PRIMARY KEY);" || m.Down != "DROP TABLE users;" {
+		t.Errorf("unexpected migration: %+v", m)
+	}
+	if Latest(migrations) != 10 || Latest(nil) != 0 {
+		t.Errorf("got latest %v, want 10", Latest(migrations))
+	}
+
+	tests := map[string]fstest.MapFS{
+		"not named":                   {"schema/users.sql": {}},
+		"no numeric":                  {"schema/one_users.sql": {}},
+		"duplicate migration version": {"schema/001_users.sql": {}, "schema/01_feeds.sql": {}},
+	}
+	for want, fsys := range tests {
+		if _, err := Load(fsys, "schema"); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got %v, want an error containing '%v'", err, want)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m := New(db, SQLite)
+	migrations, err := Load(testMigrations, "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the state of an empty database leaves it untouched
+	pending, err := m.Pending(ctx, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 3 {
+		t.Errorf("got pending %v, want every migration", versionsOf(pending))
+	}
+	if current, err := m.Current(ctx); err != nil || current != 0 {
+		t.Errorf("got version %v %v, want 0", current, err)
+	}
+	if _, err := m.Statuses(ctx, migrations); err != nil {
+		t.Fatal(err)
+	}
+	if rolledBack, err := m.Down(ctx, migrations); err != nil || rolledBack != nil {
+		t.Errorf("got %v %v, want nothing to roll back", rolledBack, err)
+	}
+	if hasTable(t, db, versionTable) {
+		t.Fatalf("reading the schema version created %v", versionTable)
+	}
+
+	done, err := m.Up(ctx, migrations)
+	if err != nil || len(done) != 3 {
+		t.Fatalf("got %v %v, want every migration applied", versionsOf(done), err)
+	}
+	if !hasTable(t, db, "posts") {
+		t.Error("migrations not run")
+	}
+	if done, err := m.Up(ctx, migrations); err != nil || len(done) != 0 {
+		t.Errorf("got %v %v, want nothing left to apply", versionsOf(done), err)
+	}
+
+	// A version missing below the highest applied one is still pending
+	_, err = db.Exec(`DELETE FROM ` + versionTable + ` WHERE version_id = 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := m.Current(ctx); current != 10 {
+		t.Errorf("got version %v, want 10", current)
+	}
+	pending, err = m.Pending(ctx, migrations)
+	if err != nil || len(pending) != 1 || pending[0].Version != 2 {
+		t.Errorf("got pending %v %v, want [2]", versionsOf(pending), err)
+	}
+	statuses, err := m.Statuses(ctx, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range statuses {
+		if st.Applied != (st.Version != 2) {
+			t.Errorf("version %v: got applied %v", st.Version, st.Applied)
+		}
+	}
+
+	rolledBack, err := m.Down(ctx, migrations)
+	if err != nil || rolledBack == nil || rolledBack.Version != 10 {
+		t.Fatalf("got %v %v, want 10 rolled back", rolledBack, err)
+	}
+	if hasTable(t, db, "posts") {
+		t.Error("down migration not run")
+	}
+	if current, _ := m.Current(ctx); current != 1 {
+		t.Errorf("got version %v, want 1", current)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m := New(db, SQLite)
+	fsys := fstest.MapFS{
+		"schema/001_users.sql": testMigrations["schema/001_users.sql"],
+		"schema/002_broken.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE broken (id INTEGER PRIMARY KEY);
+CREATE TABLE broken (id INTEGER PRIMARY KEY);
+`)},
+	}
+	migrations, err := Load(fsys, "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := m.Up(ctx, migrations)
+	if err == nil || !strings.Contains(err.Error(), "migration 2_broken failed") {
+		t.Errorf("got %v, want the broken migration to fail", err)
+	}
+	if len(done) != 1 {
+		t.Errorf("got %v applied, want [1]", versionsOf(done))
+	}
+	if hasTable(t, db, "broken") {
+		t.Error("failed migration left its changes behind")
+	}
+	if current, _ := m.Current(ctx); current != 1 {
+		t.Errorf("got version %v, want 1", current)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	m := New(db, SQLite)
+	migrations, err := Load(testMigrations, "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tables of the first two migrations were created by hand
+	for _, mig := range migrations[:2] {
+		if _, err := db.Exec(mig.Up); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing, err := m.MissingTables(ctx, []string{"users", "posts", "feeds", versionTable})
+	if err != nil || strings.Join(missing, ",") != "posts,"+versionTable {
+		t.Fatalf("got missing %v %v, want posts and %v", missing, err, versionTable)
+	}
+
+	recorded, err := m.Baseline(ctx, migrations, 2)
+	if err != nil || len(recorded) != 2 {
+		t.Fatalf("got %v %v, want [1 2] recorded", versionsOf(recorded), err)
+	}
+	if hasTable(t, db, "posts") {
+		t.Error("baseline ran a migration past its version")
+	}
+	done, err := m.Up(ctx, migrations)
+	if err != nil || len(done) != 1 || done[0].Version != 10 {
+		t.Fatalf("got %v %v, want only 10 applied", versionsOf(done), err)
+	}
+	if recorded, err := m.Baseline(ctx, migrations, 2); err != nil || len(recorded) != 0 {
+		t.Errorf("got %v %v, want nothing left to record", versionsOf(recorded), err)
+	}
+}
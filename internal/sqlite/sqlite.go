@@ -0,0 +1,130 @@
+// Package sqlite runs gator's queries against a SQLite database file.
+//
+// Queries reuses the code sqlc generates for Postgres in package database instead of a second
+// sqlc target. The queries in sql/queries keep to SQL both databases run the same way: numbered
+// $n parameters, RETURNING, ON CONFLICT, COALESCE and subqueries, with no casts, arrays or
+// Postgres functions. The schemas in sql/schema and sql/sqlite/schema have the same tables and
+// columns, UUIDs being TEXT in SQLite, which uuid.UUID writes and scans as it does for Postgres.
+// A second target would generate the same Go twice and let the two copies drift apart.
+//
+// The one query SQLite cannot run, CreateFeedFollow inserting from inside a CTE, is overridden here.
+// sqlite_test.go runs the queries gator depends on against a migrated database file, so a new query
+// leaving the shared subset fails there. Such a query gets an override here as well.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	_ "modernc.org/sqlite"
+)
+
+// Open opens the database file at path, creating it if needed, with foreign keys
+// enforced so deletes cascade the same way they do in Postgres.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, sharing one connection avoids 'database is locked' errors
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+type Queries struct {
+	*database.Queries
+	db database.DBTX
+}
+
+var _ database.Querier = (*Queries)(nil)
+
+func New(db database.DBTX) *Queries {
+	utc := utcDB{db}
+	return &Queries{
+		Queries: database.New(utc),
+		db:      utc,
+	}
+}
+
+// WithTx returns queries running in tx.
+// It replaces database.Queries.WithTx, which would lose the overrides and the conversion of times to UTC.
+func (q *Queries) WithTx(tx *sql.Tx) *Queries {
+	return New(tx)
+}
+
+const createFeedFollow = `
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+const getCreatedFeedFollow = `
+SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title,
+    feeds.name AS feed_name, users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+INNER JOIN users
+ON users.id = feed_follows.user_id
+WHERE feed_follows.id = $1
+`
+
+// CreateFeedFollow replaces the Postgres query, which inserts from inside a CTE, with an insert followed by a select.
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	_, err := q.db.ExecContext(ctx, createFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+	row := q.db.QueryRowContext(ctx, getCreatedFeedFollow, arg.ID)
+	var i database.CreateFeedFollowRow
+	err = row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
+		&i.FeedName,
+		&i.UserName,
+	)
+	return i, err
+}
+
+// utcDB stores every time in UTC.
+// SQLite keeps times as text, so times in different zones would otherwise sort incorrectly.
+type utcDB struct {
+	database.DBTX
+}
+
+func (u utcDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return u.DBTX.ExecContext(ctx, query, toUTC(args)...)
+}
+
+func (u utcDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return u.DBTX.QueryContext(ctx, query, toUTC(args)...)
+}
+
+func (u utcDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return u.DBTX.QueryRowContext(ctx, query, toUTC(args)...)
+}
+
+func toUTC(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case sql.NullTime:
+			args[i] = sql.NullTime{Time: v.Time.UTC(), Valid: v.Valid}
+		}
+	}
+	return args
+}
@@ -0,0 +1,290 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/ChipsAhoyEnjoyer/gator/internal/migrate"
+	"github.com/google/uuid"
+)
+
+// openMigrated opens a database file in a temporary directory with every migration of sql/sqlite/schema applied
+func openMigrated(t *testing.T) (*sql.DB, *Queries) {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrations, err := migrate.Load(os.DirFS("../../sql/sqlite/schema"), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations found")
+	}
+	_, err = migrate.New(db, migrate.SQLite).Up(context.Background(), migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, New(db)
+}
+
+func TestQueries(t *testing.T) {
+	ctx := context.Background()
+	db, q := openMigrated(t)
+	// Times are given in another zone than UTC, as they are stored in UTC and must still sort correctly
+	zone := time.FixedZone("UTC+5", 5*60*60)
+	at := time.Date(2025, time.March, 1, 12, 0, 0, 0, zone)
+
+	// register
+	users := make(map[string]database.User)
+	for _, name := range []string{"alice", "bob"} {
+		user, err := q.CreateUser(ctx, database.CreateUserParams{
+			ID:        uuid.New(),
+			CreatedAt: at,
+			UpdatedAt: at,
+			Name:      name,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user
+	}
+	if _, err := q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), Name: "alice"}); err == nil {
+		t.Error("registering a name twice should fail")
+	}
+	alice, err := q.GetUser(ctx, "alice")
+	if err != nil || alice.ID != users["alice"].ID || !alice.CreatedAt.Equal(at) {
+		t.Fatalf("got %+v %v, want alice as registered", alice, err)
+	}
+
+	// addfeed, which follows the feed too
+	addFeed := func(name, url string, user database.User) database.Feed {
+		t.Helper()
+		feed, err := q.PostFeed(ctx, database.PostFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: at,
+			UpdatedAt: at,
+			Name:      name,
+			Url:       url,
+			UserID:    user.ID,
+			Link:      sql.NullString{String: "https://example.com/", Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		follow(t, q, user, feed, at)
+		return feed
+	}
+	old := addFeed("Old", "https://example.com/old.xml", users["alice"])
+	target := addFeed("New", "https://example.com/rss.xml", users["bob"])
+	if _, err := q.PostFeed(ctx, database.PostFeedParams{ID: uuid.New(), Name: "Again", Url: old.Url, UserID: alice.ID}); err == nil {
+		t.Error("registering a url twice should fail")
+	}
+
+	// follow, which returns the names of the feed and user
+	row := follow(t, q, users["bob"], old, at)
+	if row.FeedName != "Old" || row.UserName != "bob" || !row.CreatedAt.Equal(at) {
+		t.Errorf("unexpected follow: %+v", row)
+	}
+	if _, err := q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ID: uuid.New(), UserID: users["bob"].ID, FeedID: old.ID}); err == nil {
+		t.Error("following a feed twice should fail")
+	}
+	follows, err := q.GetFeedFollowsForUser(ctx, users["bob"].ID)
+	if err != nil || len(follows) != 2 {
+		t.Fatalf("got %+v %v, want bob following both feeds", follows, err)
+	}
+
+	// savePosts, one a day from the oldest, with zones mixed as feeds mix them
+	var posts []database.Post
+	for i := range 4 {
+		published := at.AddDate(0, 0, i)
+		if i%2 == 1 {
+			published = published.UTC()
+		}
+		post, err := q.PostPost(ctx, database.PostPostParams{
+			ID:          uuid.New(),
+			CreatedAt:   at,
+			UpdatedAt:   at,
+			Title:       fmt.Sprintf("Post %v", i+1),
+			Url:         fmt.Sprintf("https://example.com/%v", i+1),
+			PublishedAt: published,
+			FeedID:      old.ID,
+			Author:      sql.NullString{String: "Ann", Valid: true},
+			Categories:  sql.NullString{String: "go\nsql", Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		posts = append(posts, post)
+	}
+	if posts[0].Author.String != "Ann" || posts[0].Categories.String != "go\nsql" {
+		t.Errorf("saved post lost its author or categories: %+v", posts[0])
+	}
+	// A url saved before is left as it is, with no row returned, as savePosts expects of every backend
+	if _, err := q.PostPost(ctx, database.PostPostParams{ID: uuid.New(), Title: "Again", Url: posts[0].Url, FeedID: old.ID}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("got %v saving a url twice, want sql.ErrNoRows", err)
+	}
+	if post, err := q.GetPost(ctx, posts[0].ID); err != nil || post.Title != "Post 1" {
+		t.Errorf("got %+v %v, want the saved post unchanged", post, err)
+	}
+
+	// browse, newest first, leaving out hidden posts
+	err = q.SetPostHidden(ctx, database.SetPostHiddenParams{
+		UserID:   users["alice"].ID,
+		PostID:   posts[1].ID,
+		HiddenAt: sql.NullTime{Time: at, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	browse := func(user database.User, hidden bool) []string {
+		t.Helper()
+		rows, err := q.GetPostsForUser(ctx, database.GetPostsForUserParams{
+			UserID: user.ID,
+			Hidden: hidden,
+			Limit:  10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, row := range rows {
+			titles = append(titles, row.Title+" from "+row.FeedTitle)
+		}
+		return titles
+	}
+	if got := fmt.Sprint(browse(users["alice"], false)); got != "[Post 4 from Old Post 3 from Old Post 1 from Old]" {
+		t.Errorf("alice browsed %v", got)
+	}
+	if got := fmt.Sprint(browse(users["alice"], true)); got != "[Post 2 from Old]" {
+		t.Errorf("alice browsed hidden %v", got)
+	}
+	if got := fmt.Sprint(browse(users["bob"], false)); got != "[Post 4 from Old Post 3 from Old Post 2 from Old Post 1 from Old]" {
+		t.Errorf("bob browsed %v", got)
+	}
+
+	// relocate, merging the old feed into the one registered at its new url in one transaction
+	_, err = q.CreateRule(ctx, database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: at,
+		UserID:    users["alice"].ID,
+		FeedID:    uuid.NullUUID{UUID: old.ID, Valid: true},
+		Field:     "title",
+		Pattern:   "Post",
+		Action:    "star",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txq := q.WithTx(tx)
+	moved, err := txq.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{ToFeedID: target.ID, UpdatedAt: at, FromFeedID: old.ID})
+	if err != nil || moved != 1 {
+		t.Fatalf("moved %v follow(s) %v, want only alice's", moved, err)
+	}
+	moved, err = txq.MovePosts(ctx, database.MovePostsParams{ToFeedID: target.ID, UpdatedAt: at, FromFeedID: old.ID})
+	if err != nil || moved != 4 {
+		t.Fatalf("moved %v post(s) %v, want 4", moved, err)
+	}
+	err = txq.MoveRules(ctx, database.MoveRulesParams{
+		ToFeedID:   uuid.NullUUID{UUID: target.ID, Valid: true},
+		FromFeedID: uuid.NullUUID{UUID: old.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txq.DeleteFeed(ctx, old.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.GetFeedByURL(ctx, old.Url); err == nil {
+		t.Error("merged feed not deleted")
+	}
+	if got := fmt.Sprint(browse(users["bob"], false)); got != "[Post 4 from New Post 3 from New Post 2 from New Post 1 from New]" {
+		t.Errorf("bob browsed %v after the merge", got)
+	}
+	if rules, err := q.GetRulesForFeed(ctx, target.ID); err != nil || len(rules) != 1 {
+		t.Errorf("got rules %+v %v, want alice's rule moved", rules, err)
+	}
+
+	// prune, keeping the newest two posts and the starred one
+	err = q.SetPostStarred(ctx, database.SetPostStarredParams{
+		UserID:    users["bob"].ID,
+		PostID:    posts[0].ID,
+		StarredAt: sql.NullTime{Time: at, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	arg := database.PrunePostsParams{
+		FeedID:      target.ID,
+		KeepPosts:   sql.NullInt64{Int64: 2, Valid: true},
+		UnreadSince: at.AddDate(1, 0, 0),
+	}
+	toPrune, err := q.GetPostsToPrune(ctx, database.GetPostsToPruneParams(arg))
+	if err != nil || len(toPrune) != 1 || toPrune[0].ID != posts[1].ID {
+		t.Fatalf("got %+v %v, want only post 2 to prune", toPrune, err)
+	}
+	pruned, err := q.PrunePosts(ctx, arg)
+	if err != nil || pruned != 1 {
+		t.Fatalf("pruned %v post(s) %v, want 1", pruned, err)
+	}
+	if got := fmt.Sprint(browse(users["bob"], false)); got != "[Post 4 from New Post 3 from New Post 1 from New]" {
+		t.Errorf("bob browsed %v after pruning", got)
+	}
+	if hidden := browse(users["alice"], true); len(hidden) != 0 {
+		t.Errorf("state of the pruned post left behind: %v", hidden)
+	}
+	if n, err := q.CountPostsForFeed(ctx, target.ID); err != nil || n != 3 {
+		t.Errorf("got %v post(s) %v, want 3 left", n, err)
+	}
+}
+
+func TestTimesAreStoredInUTC(t *testing.T) {
+	ctx := context.Background()
+	db, q := openMigrated(t)
+	at := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.FixedZone("UTC-8", -8*60*60))
+	user, err := q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: at, UpdatedAt: at, Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored string
+	if err := db.QueryRow(`SELECT created_at FROM users WHERE name = 'alice'`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := time.Parse(time.RFC3339Nano, stored); err != nil || got.Location() != time.UTC || !got.Equal(at) {
+		t.Errorf("stored %q, want %v in UTC", stored, at.UTC())
+	}
+	if !user.CreatedAt.Equal(at) {
+		t.Errorf("got %v, want %v", user.CreatedAt, at)
+	}
+}
+
+func follow(t *testing.T, q *Queries, user database.User, feed database.Feed, at time.Time) database.CreateFeedFollowRow {
+	t.Helper()
+	row, err := q.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: at,
+		UpdatedAt: at,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return row
+}
@@ -1,49 +1,174 @@
 package main
 
 import (
-	"database/sql"
+	"cmp"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
-	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
 	_ "github.com/lib/pq"
 )
 
 func main() {
 	gatorState := createStateInstance()
-	newConfig, err := config.Read()
+	commandRegistry := newCommands()
+	cmd, opts, err := commandRegistry.cleanInput(os.Args)
+	if err != nil {
+		exit(gatorState, err)
+	}
+	newConfig, err := config.Read(opts.profile)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exit(gatorState, err)
+	}
+	if opts.dbURL != "" {
+		newConfig.Override(config.KeyDBUrl, opts.dbURL, "--db-url")
+	}
+	if opts.user != "" {
+		newConfig.Override(config.KeyCurrentUsername, opts.user, "--user")
 	}
 	gatorState.cfg = newConfig
-	commandRegistry := newCommands()
-	db, err := sql.Open("postgres", gatorState.cfg.DBUrl)
+	output := opts.output
+	if output == "" {
+		output = cmp.Or(newConfig.Output, string(outputTable))
+	}
+	gatorState.format, err = parseOutputFormat(output)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exit(gatorState, err)
+	}
+	if gatorState.cfg.DBUrl == "" && commandRegistry.needsSchema(*cmd) {
+		exit(gatorState, fmt.Errorf(
+			"error: no database configured, run 'gator init' to set gator up\nor set db_url in '%v', GATOR_DB_URL or --db-url",
+			gatorState.cfg.Path(),
+		))
 	}
-	gatorState.db = database.New(db)
-	cmd, err := cleanInput(os.Args)
+	err = openStorage(gatorState, gatorState.cfg.DBUrl)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exit(gatorState, err)
+	}
+	if commandRegistry.needsSchema(*cmd) {
+		err = checkSchema(gatorState)
+		if err != nil {
+			exit(gatorState, err)
+		}
 	}
 	err = commandRegistry.run(gatorState, *cmd)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exit(gatorState, err)
+	}
+}
+
+// exit reports err and ends gator with the matching exit code
+func exit(s *state, err error) {
+	fmt.Fprintln(s.errOut, err)
+	os.Exit(exitCode(err))
+}
+
+// globalFlags are accepted by every command
+var globalFlags = []flagDef{
+	{
+		name:     "output",
+		short:    "o",
+		value:    "table|json|ndjson|csv|yaml",
+		usage:    "Format of listing commands' results (defaults to the profile's output setting, else table)",
+		complete: choices("table", "json", "ndjson", "csv", "yaml"),
+	},
+	{
+		name:     "profile",
+		value:    "name",
+		usage:    "Profile of the config file to use instead of the active one, overriding GATOR_PROFILE",
+		complete: completeProfiles,
+	},
+	{
+		name:  "db-url",
+		value: "url",
+		usage: "Database to use instead of the config file's db_url, overriding GATOR_DB_URL",
+	},
+	{
+		name:     "user",
+		value:    "username",
+		usage:    "User to act as instead of the config file's current_user_name, overriding GATOR_USER",
+		complete: completeUsernames,
+	},
+}
+
+// globalOptions holds the values of globalFlags
+type globalOptions struct {
+	// output is empty when not given, leaving the format to the profile
+	output  string
+	profile string
+	dbURL   string
+	user    string
+}
+
+// cleanInput splits the command line into a command and the global flags,
+// which may appear anywhere before a '--' argument. After the command, a flag
+// the command defines itself, such as 'reset --user', is left to the command.
+func (c *commands) cleanInput(input []string) (*command, globalOptions, error) {
+	values := map[string]string{}
+	args := []string{}
+	for i := 1; i < len(input); i++ {
+		arg := input[i]
+		if arg == "--" {
+			args = append(args, input[i:]...)
+			break
+		}
+		f, value, hasValue, ok := globalFlag(arg)
+		if ok && c.hasOwnFlag(args, f.name) {
+			ok = false
+			if !hasValue && f.value != "" && i+1 < len(input) {
+				args = append(args, arg)
+				i++
+				arg = input[i]
+			}
+		}
+		if !ok {
+			args = append(args, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(input) {
+				return nil, globalOptions{}, usageErrorf("usage: --%v %v", f.name, f.value)
+			}
+			i++
+			value = input[i]
+		}
+		values[f.name] = value
 	}
+	opts := globalOptions{output: values["output"], profile: values["profile"]}
+	opts.dbURL = values["db-url"]
+	opts.user = values["user"]
+	if len(args) == 0 {
+		return nil, opts, usageErrorf("usage: gator [global flags] <command> [args...]\nuse 'gator help' to list commands")
+	}
+	return &command{name: args[0], args: args[1:]}, opts, nil
+}
 
+// hasOwnFlag reports whether the command named by args defines a flag called name
+func (c *commands) hasOwnFlag(args []string, name string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	def, _, err := c.resolve(command{name: args[0], args: args[1:]})
+	if err != nil {
+		return false
+	}
+	_, ok := def.flagDef(name)
+	return ok
 }
 
-func cleanInput(input []string) (*command, error) {
-	if len(input) < 2 {
-		return nil, fmt.Errorf("usage: cli <command> [args...]")
+// globalFlag matches arg against globalFlags, returning the value when given as '--flag=value'
+func globalFlag(arg string) (flagDef, string, bool, bool) {
+	if !strings.HasPrefix(arg, "-") {
+		return flagDef{}, "", false, false
+	}
+	name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	for _, f := range globalFlags {
+		long := strings.HasPrefix(arg, "--") && name == f.name
+		short := !strings.HasPrefix(arg, "--") && name == f.short
+		if long || short {
+			return f, value, hasValue, true
+		}
 	}
-	var cmd command
-	cmd.name = os.Args[1]
-	cmd.args = os.Args[2:]
-	return &cmd, nil
+	return flagDef{}, "", false, false
 }
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Events recorded in feed_history
+const (
+	feedEventRenamed = "renamed"
+	feedEventURLSet  = "url changed"
+	feedEventMoved   = "moved permanently"
+	feedEventMerged  = "merged"
+)
+
+func canManageFeed(user database.User, feed database.Feed) bool {
+	return feed.UserID == user.ID || hasAdminRights(user)
+}
+
+// managedFeed looks up the feed registered under url and checks user may change it
+func managedFeed(s *state, user database.User, url string) (database.Feed, error) {
+	feed, err := s.db.GetFeedByURL(context.Background(), url)
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("error: feed '%v' not registered, use 'gator feeds' to see existing feeds", url)
+	}
+	if !canManageFeed(user, feed) {
+		return database.Feed{}, fmt.Errorf("error: only the user who added '%v' or an admin can change it", feed.Name)
+	}
+	return feed, nil
+}
+
+func handlerFeedRemove(s *state, cmd command, user database.User) error {
+	feed, err := managedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	follows, err := s.db.CountFollowsForFeed(context.Background(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not count follows of '%v' \n%v", feed.Name, err)
+	}
+	posts, err := s.db.CountPostsForFeed(context.Background(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not count posts of '%v' \n%v", feed.Name, err)
+	}
+	err = s.db.DeleteFeed(context.Background(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not delete feed '%v' \n%v", feed.Name, err)
+	}
+	fmt.Fprintln(s.out, "================================================================")
+	fmt.Fprintf(s.out, "Deleted feed '%v' (%v)\n", feed.Name, feed.Url)
+	fmt.Fprintf(s.out, "Removed with it: %v follow(s), %v post(s)\n", follows, posts)
+	fmt.Fprintln(s.out, "================================================================")
+	return nil
+}
+
+func handlerFeedRename(s *state, cmd command, user database.User) error {
+	name := strings.TrimSpace(cmd.args[1])
+	if name == "" {
+		return usageErrorf("usage: gator feed rename <url> <name>, the name can't be empty")
+	}
+	feed, err := managedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	row, err := s.db.UpdateFeedName(
+		context.Background(),
+		database.UpdateFeedNameParams{
+			Name:      name,
+			UpdatedAt: time.Now(),
+			ID:        feed.ID,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not rename feed '%v' \n%v", feed.Name, err)
+	}
+	err = logFeedHistory(s, feed.ID, feedEventRenamed, feed.Name, row.Name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Renamed '%v' to '%v'\n", feed.Name, row.Name)
+	return nil
+}
+
+func handlerFeedSetURL(s *state, cmd command, user database.User) error {
+	feed, err := managedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	newURL := cmd.args[1]
+	if existing, err := s.db.GetFeedByURL(context.Background(), newURL); err == nil {
+		return fmt.Errorf("error: '%v' is already registered as '%v'", newURL, existing.Name)
+	}
+	_, location, err := fetchFeed(context.Background(), newURL)
+	if err != nil {
+		return fmt.Errorf("error: %v is not a valid feed \n%v", newURL, err)
+	}
+	if location != newURL {
+		fmt.Fprintf(s.out, "%v has moved permanently to %v\n", newURL, location)
+		newURL = location
+		if existing, err := s.db.GetFeedByURL(context.Background(), newURL); err == nil {
+			return fmt.Errorf("error: '%v' is already registered as '%v'", newURL, existing.Name)
+		}
+	}
+	row, err := s.db.UpdateFeedURL(
+		context.Background(),
+		database.UpdateFeedURLParams{
+			Url:       newURL,
+			UpdatedAt: time.Now(),
+			ID:        feed.ID,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not update url of '%v' \n%v", feed.Name, err)
+	}
+	err = logFeedHistory(s, feed.ID, feedEventURLSet, feed.Url, row.Url)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "'%v' now fetched from %v (was %v)\n", row.Name, row.Url, feed.Url)
+	fmt.Fprintln(s.out, "Follows and posts are unchanged")
+	return nil
+}
+
+func handlerFeedHistory(s *state, cmd command) error {
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("error: feed '%v' not registered, use 'gator feeds' to see existing feeds", cmd.args[0])
+	}
+	history, err := s.db.GetFeedHistory(context.Background(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not retreive history of '%v' \n%v", feed.Name, err)
+	}
+	if s.format != outputTable {
+		records := make([]feedHistoryRecord, len(history))
+		for i, h := range history {
+			records[i] = newFeedHistoryRecord(h)
+		}
+		return writeRecords(s, records)
+	}
+	fmt.Fprintf(s.out, "=============================HISTORY============================\n")
+	fmt.Fprintf(s.out, "Feed: %v (%v)\n\n", feed.Name, feed.Url)
+	for _, h := range history {
+		fmt.Fprintf(s.out, "%v  %v: %v -> %v\n", h.CreatedAt.Format(time.DateTime), h.Event, h.OldValue.String, h.NewValue.String)
+	}
+	fmt.Fprintln(s.out, "================================================================")
+	return nil
+}
+
+func logFeedHistory(s *state, feedID uuid.UUID, event, oldValue, newValue string) error {
+	err := s.db.CreateFeedHistory(
+		context.Background(),
+		database.CreateFeedHistoryParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			FeedID:    feedID,
+			Event:     event,
+			OldValue:  nullString(oldValue),
+			NewValue:  nullString(newValue),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not record feed history \n%v", err)
+	}
+	return nil
+}
+
+// relocateFeed points feed at the url it has permanently moved to.
+// If another feed is already registered there, feed's follows, posts, history and rules
+// are merged into it and feed is deleted, all in one transaction. The feed now serving newURL is returned.
+func relocateFeed(s *state, feed database.Feed, newURL string) (database.Feed, error) {
+	target, err := s.db.GetFeedByURL(context.Background(), newURL)
+	if err != nil {
+		var row database.Feed
+		err = inTx(s, func(tx *state) error {
+			var err error
+			row, err = tx.db.UpdateFeedURL(
+				context.Background(),
+				database.UpdateFeedURLParams{
+					Url:       newURL,
+					UpdatedAt: time.Now(),
+					ID:        feed.ID,
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("error: could not update url of '%v' \n%v", feed.Name, err)
+			}
+			return logFeedHistory(tx, feed.ID, feedEventMoved, feed.Url, newURL)
+		})
+		if err != nil {
+			return database.Feed{}, err
+		}
+		fmt.Fprintf(s.out, "'%v' moved permanently from %v to %v\n", feed.Name, feed.Url, newURL)
+		return row, nil
+	}
+
+	var follows, posts int64
+	err = inTx(s, func(tx *state) error {
+		var err error
+		follows, posts, err = mergeFeed(tx, feed, target)
+		if err != nil {
+			return err
+		}
+		return logFeedHistory(tx, target.ID, feedEventMerged, feed.Url, newURL)
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+	fmt.Fprintf(s.out, "'%v' moved permanently to %v, already registered as '%v'\n", feed.Name, newURL, target.Name)
+	fmt.Fprintf(s.out, "Merged into '%v': %v follow(s), %v post(s)\n", target.Name, follows, posts)
+	return target, nil
+}
+
+// mergeFeed moves the follows, posts, history and rules of feed to target and deletes feed,
+// returning how many follows and posts moved. Run it in a transaction so a failed step leaves both feeds as they were.
+func mergeFeed(s *state, feed, target database.Feed) (int64, int64, error) {
+	follows, err := s.db.MoveFeedFollows(
+		context.Background(),
+		database.MoveFeedFollowsParams{
+			ToFeedID:   target.ID,
+			UpdatedAt:  time.Now(),
+			FromFeedID: feed.ID,
+		},
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error: could not move follows of '%v' to '%v' \n%v", feed.Name, target.Name, err)
+	}
+	posts, err := s.db.MovePosts(
+		context.Background(),
+		database.MovePostsParams{
+			ToFeedID:   target.ID,
+			UpdatedAt:  time.Now(),
+			FromFeedID: feed.ID,
+		},
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error: could not move posts of '%v' to '%v' \n%v", feed.Name, target.Name, err)
+	}
+	err = s.db.MoveFeedHistory(
+		context.Background(),
+		database.MoveFeedHistoryParams{
+			ToFeedID:   target.ID,
+			FromFeedID: feed.ID,
+		},
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error: could not move history of '%v' to '%v' \n%v", feed.Name, target.Name, err)
+	}
+	err = s.db.MoveRules(
+		context.Background(),
+		database.MoveRulesParams{
+			ToFeedID:   uuid.NullUUID{UUID: target.ID, Valid: true},
+			FromFeedID: uuid.NullUUID{UUID: feed.ID, Valid: true},
+		},
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error: could not move rules of '%v' to '%v' \n%v", feed.Name, target.Name, err)
+	}
+	err = s.db.DeleteFeed(context.Background(), feed.ID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error: could not delete feed '%v' \n%v", feed.Name, err)
+	}
+	return follows, posts, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/ChipsAhoyEnjoyer/gator/internal/memory"
+	"github.com/google/uuid"
+)
+
+func TestFeedManagementIsLimitedToCreator(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	url := srv.URL + "/rss.xml"
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", url, "--seed")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", url)
+
+	for _, args := range [][]string{{"rename", url, "Mine now"}, {"rm", url}} {
+		if err := run(t, s, "feed", args...); err == nil {
+			t.Errorf("feed %v by a user who didn't add it should fail", args[0])
+		}
+	}
+
+	mustRun(t, s, "login", "alice")
+	for _, name := range []string{"", "  "} {
+		if err := run(t, s, "feed", "rename", url, name); exitCode(err) != exitUsage {
+			t.Errorf("renaming to %q: got %v, want a usage error", name, err)
+		}
+	}
+	mustRun(t, s, "feed", "rename", url, "Renamed")
+	feed, _ := s.db.GetFeedByURL(context.Background(), url)
+	if feed.Name != "Renamed" {
+		t.Fatalf("name = %q after rename", feed.Name)
+	}
+	history, _ := s.db.GetFeedHistory(context.Background(), feed.ID)
+	if len(history) != 1 || history[0].Event != feedEventRenamed {
+		t.Fatalf("rename should be recorded in history, got %+v", history)
+	}
+
+	mustRun(t, s, "feed", "rm", url)
+	if _, err := s.db.GetFeedByURL(context.Background(), url); err == nil {
+		t.Fatal("feed still exists after rm")
+	}
+	for _, name := range []string{"alice", "bob"} {
+		user, _ := s.db.GetUser(context.Background(), name)
+		follows, _ := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+		if len(follows) != 0 {
+			t.Errorf("%v still follows the removed feed", name)
+		}
+	}
+	posts, _ := s.db.CountPostsForFeed(context.Background(), feed.ID)
+	if posts != 0 {
+		t.Errorf("%v posts left after rm", posts)
+	}
+}
+
+func TestScrapeFeedsFollowsPermanentRedirects(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Temporary", srv.URL+"/temporary.xml")
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.GetFeedByURL(context.Background(), srv.URL+"/temporary.xml"); err != nil {
+		t.Error("a temporary redirect should keep the stored url")
+	}
+
+	mustRun(t, s, "feed", "rm", srv.URL+"/temporary.xml")
+
+	// addfeed already stores where a feed has moved to, so register the old url directly
+	alice, _ := s.db.GetUser(context.Background(), "alice")
+	old, err := s.db.PostFeed(context.Background(), database.PostFeedParams{
+		ID:     uuid.New(),
+		Name:   "Old",
+		Url:    srv.URL + "/old.xml",
+		UserID: alice.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatal(err)
+	}
+	feed, err := s.db.GetFeedByURL(context.Background(), srv.URL+"/rss.xml")
+	if err != nil || feed.ID != old.ID {
+		t.Fatalf("feed should be stored under the url it moved to, got %+v, %v", feed, err)
+	}
+	history, _ := s.db.GetFeedHistory(context.Background(), feed.ID)
+	if len(history) != 1 || history[0].Event != feedEventMoved || history[0].OldValue.String != srv.URL+"/old.xml" {
+		t.Errorf("move should be recorded in history, got %+v", history)
+	}
+}
+
+func TestScrapeFeedsMergesIntoExistingFeed(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "New", srv.URL+"/rss.xml")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "addfeed", "New", srv.URL+"/temporary.xml")
+
+	// Make the second feed look like it was registered under a url that has since moved
+	old, _ := s.db.GetFeedByURL(context.Background(), srv.URL+"/temporary.xml")
+	target, _ := s.db.GetFeedByURL(context.Background(), srv.URL+"/rss.xml")
+	merged, err := relocateFeed(s, old, target.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.ID != target.ID {
+		t.Fatalf("relocating onto a registered url should return that feed")
+	}
+	if _, err := s.db.GetFeedByURL(context.Background(), srv.URL+"/temporary.xml"); err == nil {
+		t.Error("the moved feed should be deleted after merging")
+	}
+	bob, _ := s.db.GetUser(context.Background(), "bob")
+	feeds, _ := s.db.GetFollowedFeedsForUser(context.Background(), bob.ID)
+	if len(feeds) != 1 || feeds[0].ID != target.ID {
+		t.Errorf("bob's follow should move to the merged feed, got %+v", feeds)
+	}
+	history, _ := s.db.GetFeedHistory(context.Background(), target.ID)
+	if len(history) != 1 || history[0].Event != feedEventMerged {
+		t.Errorf("merge should be recorded in history, got %+v", history)
+	}
+}
+
+// failingRuleMoves fails to move rules, the last step of merging feeds before deleting the merged one
+type failingRuleMoves struct {
+	*memory.Queries
+}
+
+func (f failingRuleMoves) MoveRules(ctx context.Context, arg database.MoveRulesParams) error {
+	return errors.New("disk full")
+}
+
+func TestMergeFeedsRollsBackOnFailure(t *testing.T) {
+	backends := map[string]struct {
+		newState func(t *testing.T) *state
+		// breakMoves makes moving rules fail and returns a function undoing it
+		breakMoves func(t *testing.T, s *state) func()
+	}{
+		"memory": {
+			newState: newTestState,
+			breakMoves: func(t *testing.T, s *state) func() {
+				db := s.db
+				s.db = failingRuleMoves{db.(*memory.Queries)}
+				return func() { s.db = db }
+			},
+		},
+		"sqlite": {
+			newState: newSQLiteState,
+			breakMoves: func(t *testing.T, s *state) func() {
+				_, err := s.conn.Exec(`CREATE TRIGGER fail_rule_moves BEFORE UPDATE ON rules BEGIN SELECT RAISE(ABORT, 'disk full'); END`)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return func() {
+					if _, err := s.conn.Exec(`DROP TRIGGER fail_rule_moves`); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+		},
+	}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			s := backend.newState(t)
+			seedFixture(t, s)
+			mustRun(t, s, "feed", "rename", "https://blog.example.com/rss.xml", "Renamed Blog")
+			mustRun(t, s, "rules", "add", "title", "First", "hide", "--feed", "https://blog.example.com/rss.xml")
+			ctx := context.Background()
+			feed, err := s.db.GetFeedByURL(ctx, "https://blog.example.com/rss.xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			target, err := s.db.GetFeedByURL(ctx, "https://quiet.example.com/feed")
+			if err != nil {
+				t.Fatal(err)
+			}
+			alice, err := s.db.GetUser(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// snapshot describes everything a merge changes
+			snapshot := func() string {
+				t.Helper()
+				var b strings.Builder
+				feeds, err := s.db.GetFeeds(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, f := range feeds {
+					posts, err := s.db.CountPostsForFeed(ctx, f.ID)
+					if err != nil {
+						t.Fatal(err)
+					}
+					history, err := s.db.GetFeedHistory(ctx, f.ID)
+					if err != nil {
+						t.Fatal(err)
+					}
+					fmt.Fprintf(&b, "%v: %v post(s), %v history\n", f.Name, posts, len(history))
+				}
+				follows, err := s.db.GetFeedFollowsForUser(ctx, alice.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, f := range follows {
+					fmt.Fprintf(&b, "follows %v\n", f.FeedName)
+				}
+				rules, err := s.db.GetRulesForUser(ctx, alice.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, r := range rules {
+					fmt.Fprintf(&b, "rule on %v\n", r.FeedUrl.String)
+				}
+				return b.String()
+			}
+			before := snapshot()
+
+			restore := backend.breakMoves(t, s)
+			if _, err := relocateFeed(s, feed, target.Url); err == nil || !strings.Contains(err.Error(), "could not move rules") {
+				t.Fatalf("got %v, want moving rules to fail", err)
+			}
+			if after := snapshot(); after != before {
+				t.Errorf("failed merge changed the database:\n%v\nwant:\n%v", after, before)
+			}
+
+			restore()
+			if _, err := relocateFeed(s, feed, target.Url); err != nil {
+				t.Fatal(err)
+			}
+			want := "Quiet Blog: 3 post(s), 2 history\nfollows Quiet Blog\nrule on https://quiet.example.com/feed\n"
+			if after := snapshot(); after != want {
+				t.Errorf("got:\n%v\nwant:\n%v", after, want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
 )
@@ -15,6 +16,10 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 		if err != nil {
 			return err
 		}
+		err = checkSession(s, usr)
+		if err != nil {
+			return err
+		}
 		err = handler(s, c, usr)
 		if err != nil {
 			return err
@@ -22,3 +27,22 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 		return nil
 	}
 }
+
+// hasAdminRights reports whether user may act as an admin. An admin without a password has no
+// session to check, so anyone could log in as them: they must set a password first.
+func hasAdminRights(user database.User) bool {
+	return user.IsAdmin && user.PasswordHash.Valid
+}
+
+// middlewareAdmin lets only admins, logged in with their password, run handler
+func middlewareAdmin(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+	return middlewareLoggedIn(func(s *state, c command, user database.User) error {
+		if !user.IsAdmin {
+			return fmt.Errorf("error: only admins can run 'gator %v', ask one to run it or to make you an admin", c.name)
+		}
+		if !hasAdminRights(user) {
+			return fmt.Errorf("error: admins need a password to run 'gator %v', set one with 'gator passwd'", c.name)
+		}
+		return handler(s, c, user)
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/migrate"
+)
+
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var schemaFS embed.FS
+
+// Each backend has its own migrations, kept at the same versions
+var schemaDirs = map[migrate.Dialect]string{
+	migrate.Postgres: "sql/schema",
+	migrate.SQLite:   "sql/sqlite/schema",
+}
+
+// Older READMEs had users create the schema of migrations 1 to 5 by hand, leaving no record of them
+const readmeBaselineVersion = 5
+
+var readmeBaselineTables = []string{"users", "feeds", "feed_follows", "posts"}
+
+func loadMigrations(s *state) ([]migrate.Migration, error) {
+	return migrate.Load(schemaFS, schemaDirs[s.dialect])
+}
+
+// hasReadmeBaseline reports whether the database holds the tables made by the SQL of older READMEs
+// with no migration recorded. Only some of them existing is an error, as they match no migration.
+func hasReadmeBaseline(m *migrate.Migrator) (bool, error) {
+	current, err := m.Current(context.Background())
+	if err != nil || current != 0 {
+		return false, err
+	}
+	missing, err := m.MissingTables(context.Background(), readmeBaselineTables)
+	if err != nil {
+		return false, err
+	}
+	switch len(missing) {
+	case 0:
+		return true, nil
+	case len(readmeBaselineTables):
+		return false, nil
+	}
+	return false, fmt.Errorf("error: database has some of gator's tables but no record of its migrations, %v missing\nfinish running the SQL of the README it was set up with, then run 'gator migrate up'", strings.Join(missing, ", "))
+}
+
+// checkSchema refuses to go on when the database is missing migrations this binary relies on
+func checkSchema(s *state) error {
+	migrations, err := loadMigrations(s)
+	if err != nil {
+		return err
+	}
+	m := migrate.New(s.conn, s.dialect)
+	pending, err := m.Pending(context.Background(), migrations)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if len(pending) == len(migrations) {
+		baseline, err := hasReadmeBaseline(m)
+		if err != nil {
+			return err
+		}
+		if baseline {
+			return fmt.Errorf("error: database was set up with the SQL of an older README, which gator has no record of\nrun 'gator migrate up' to record it and upgrade the database")
+		}
+		return fmt.Errorf("error: database has no gator schema yet\nrun 'gator migrate up' to create it")
+	}
+	missing := make([]string, len(pending))
+	for i, m := range pending {
+		missing[i] = fmt.Sprintf("%03d_%v", m.Version, m.Name)
+	}
+	return fmt.Errorf("error: database schema is missing migration(s) %v that gator needs\nrun 'gator migrate up' to upgrade it", strings.Join(missing, ", "))
+}
+
+func handlerMigrateUp(s *state, cmd command) error {
+	migrations, err := loadMigrations(s)
+	if err != nil {
+		return err
+	}
+	m := migrate.New(s.conn, s.dialect)
+	baseline, err := hasReadmeBaseline(m)
+	if err != nil {
+		return err
+	}
+	if baseline {
+		recorded, err := m.Baseline(context.Background(), migrations, readmeBaselineVersion)
+		for _, mig := range recorded {
+			fmt.Fprintf(s.out, "Recorded %v_%v, already in the database\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	done, err := m.Up(context.Background(), migrations)
+	for _, mig := range done {
+		fmt.Fprintf(s.out, "Applied %v_%v\n", mig.Version, mig.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Fprintln(s.out, "Database is up to date")
+	} else {
+		fmt.Fprintf(s.out, "Database migrated to version %v\n", migrate.Latest(migrations))
+	}
+	return nil
+}
+
+func handlerMigrateDown(s *state, cmd command) error {
+	migrations, err := loadMigrations(s)
+	if err != nil {
+		return err
+	}
+	m, err := migrate.New(s.conn, s.dialect).Down(context.Background(), migrations)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		fmt.Fprintln(s.out, "No migrations to roll back")
+	} else {
+		fmt.Fprintf(s.out, "Rolled back %v_%v\n", m.Version, m.Name)
+	}
+	return nil
+}
+
+func handlerMigrateStatus(s *state, cmd command) error {
+	migrations, err := loadMigrations(s)
+	if err != nil {
+		return err
+	}
+	statuses, err := migrate.New(s.conn, s.dialect).Statuses(context.Background(), migrations)
+	if err != nil {
+		return err
+	}
+	if s.format != outputTable {
+		records := make([]migrationRecord, len(statuses))
+		for i, st := range statuses {
+			records[i] = newMigrationRecord(st)
+		}
+		return writeRecords(s, records)
+	}
+	fmt.Fprintln(s.out, "============================MIGRATIONS==========================")
+	for _, st := range statuses {
+		applied := "pending"
+		if st.Applied {
+			applied = "applied " + st.AppliedAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(s.out, "%03d %-30v %v\n", st.Version, st.Name, applied)
+	}
+	fmt.Fprintln(s.out, "================================================================")
+	return nil
+}
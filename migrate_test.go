@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateUpFromReadmeSchema(t *testing.T) {
+	s := newTestState(t)
+	err := openStorage(s, sqliteScheme+filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.conn.Close() })
+	migrations, err := loadMigrations(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The README's SQL is that of the first migrations, run without goose's bookkeeping
+	for i, m := range migrations[:readmeBaselineVersion] {
+		if _, err := s.conn.Exec(m.Up); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			err := checkSchema(s)
+			if err == nil || !strings.Contains(err.Error(), "feeds, feed_follows, posts missing") {
+				t.Errorf("got %v, want the missing tables listed", err)
+			}
+		}
+	}
+	if _, err := s.conn.Exec(`INSERT INTO users (id, created_at, updated_at, name) VALUES ('00000000-0000-0000-0000-00000000a11c', '2025-03-01T12:00:00Z', '2025-03-01T12:00:00Z', 'alice')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSchema(s); err == nil || !strings.Contains(err.Error(), "older README") {
+		t.Errorf("got %v, want the README's schema recognised", err)
+	}
+
+	mustRun(t, s, "migrate", "up")
+	out := s.out.(*bytes.Buffer).String()
+	for _, want := range []string{"Recorded 1_users, already in the database\n", "Recorded 5_posts", "Applied 6_feed_link_description\n", "Database migrated"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%v", want, out)
+		}
+	}
+	if strings.Contains(out, "Applied 1_users") {
+		t.Errorf("migrations of the README's schema were run again:\n%v", out)
+	}
+	if err := checkSchema(s); err != nil {
+		t.Error(err)
+	}
+	if user, err := s.db.GetUser(context.Background(), "alice"); err != nil || !user.IsAdmin {
+		t.Errorf("got %+v %v, want alice kept and made the admin", user, err)
+	}
+}
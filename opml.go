@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    OPMLHead `xml:"head"`
+	Body    OPMLBody `xml:"body"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+	OwnerName   string `xml:"ownerName,omitempty"`
+}
+
+type OPMLBody struct {
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline,omitempty"`
+}
+
+// opmlEntry is a single subscription to be written out.
+// Entries sharing a Folder are nested under one outline, entries without one sit at the top level.
+type opmlEntry struct {
+	Title   string
+	XMLURL  string
+	HTMLURL string
+	Folder  string
+}
+
+func buildOPML(title, owner string, entries []opmlEntry) *OPML {
+	doc := &OPML{
+		Version: "2.0",
+		Head: OPMLHead{
+			Title:       title,
+			DateCreated: time.Now().Format(time.RFC1123Z),
+			OwnerName:   owner,
+		},
+	}
+	folders := make(map[string]int)
+	for _, e := range entries {
+		outline := OPMLOutline{
+			Text:    e.Title,
+			Title:   e.Title,
+			Type:    "rss",
+			XMLURL:  e.XMLURL,
+			HTMLURL: e.HTMLURL,
+		}
+		if e.Folder == "" {
+			doc.Body.Outlines = append(doc.Body.Outlines, outline)
+			continue
+		}
+		i, ok := folders[e.Folder]
+		if !ok {
+			doc.Body.Outlines = append(doc.Body.Outlines, OPMLOutline{
+				Text:  e.Folder,
+				Title: e.Folder,
+			})
+			i = len(doc.Body.Outlines) - 1
+			folders[e.Folder] = i
+		}
+		doc.Body.Outlines[i].Outlines = append(doc.Body.Outlines[i].Outlines, outline)
+	}
+	return doc
+}
+
+func writeOPML(w io.Writer, doc *OPML) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("error: could not encode OPML document \n%v", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// feedsToOPMLEntries makes an entry of each feed. A feed in follows is exported
+// with the follower's title for it, filed under their folder if it is in one.
+func feedsToOPMLEntries(feeds []database.Feed, follows map[uuid.UUID]database.GetFeedFollowsForUserRow) []opmlEntry {
+	entries := make([]opmlEntry, 0, len(feeds))
+	for _, feed := range feeds {
+		follow, ok := follows[feed.ID]
+		title := feed.Name
+		if ok {
+			title = followTitle(follow)
+		}
+		entries = append(entries, opmlEntry{
+			Title:   title,
+			XMLURL:  feed.Url,
+			HTMLURL: feed.Link.String,
+			Folder:  follow.FolderName.String,
+		})
+	}
+	return entries
+}
+
+// followsByFeed maps each feed user follows to their follow of it
+func followsByFeed(s *state, user database.User) (map[uuid.UUID]database.GetFeedFollowsForUserRow, error) {
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+	byFeed := make(map[uuid.UUID]database.GetFeedFollowsForUserRow, len(follows))
+	for _, follow := range follows {
+		byFeed[follow.FeedID] = follow
+	}
+	return byFeed, nil
+}
+
+func handlerExport(s *state, cmd command, user database.User) error {
+	all := cmd.has("all")
+	path := ""
+	if len(cmd.args) == 1 {
+		path = cmd.args[0]
+	}
+
+	var feeds []database.Feed
+	var err error
+	var follows map[uuid.UUID]database.GetFeedFollowsForUserRow
+	title := fmt.Sprintf("%v's gator subscriptions", user.Name)
+	if all {
+		feeds, err = s.db.GetFeeds(context.Background())
+		title = "gator feeds"
+	} else {
+		feeds, err = s.db.GetFollowedFeedsForUser(context.Background(), user.ID)
+		if err == nil {
+			follows, err = followsByFeed(s, user)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error: could not retreive feeds \n%v", err)
+	}
+	doc := buildOPML(title, user.Name, feedsToOPMLEntries(feeds, follows))
+
+	if path == "" {
+		return writeOPML(s.out, doc)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error: could not create file '%v' \n%v", path, err)
+	}
+	err = writeOPML(f, doc)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	fmt.Fprintf(s.out, "Exported %v feed(s) to %v\n", len(feeds), path)
+	return f.Close()
+}
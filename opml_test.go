@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportOPML(t *testing.T) {
+	s := newTestState(t)
+	srv := newFeedServer(t)
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", srv.URL+"/rss.xml")
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	mustRun(t, s, "export", "opml", path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := OPML{}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("export is not valid OPML: %v", err)
+	}
+	if doc.Version != "2.0" || len(doc.Body.Outlines) != 1 {
+		t.Fatalf("unexpected OPML: %+v", doc)
+	}
+	outline := doc.Body.Outlines[0]
+	if outline.Text != "Test Blog" || outline.XMLURL != srv.URL+"/rss.xml" || outline.HTMLURL != "https://blog.example.com/" {
+		t.Errorf("unexpected outline: %+v", outline)
+	}
+	if err := run(t, s, "export", "csv"); err == nil {
+		t.Error("exporting an unknown format should fail")
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/ChipsAhoyEnjoyer/gator/internal/migrate"
+	"github.com/google/uuid"
+)
+
+type outputFormat string
+
+const (
+	outputTable  outputFormat = "table"
+	outputJSON   outputFormat = "json"
+	outputNDJSON outputFormat = "ndjson"
+	outputCSV    outputFormat = "csv"
+	outputYAML   outputFormat = "yaml"
+)
+
+var outputFormats = []outputFormat{outputTable, outputJSON, outputNDJSON, outputCSV, outputYAML}
+
+func parseOutputFormat(name string) (outputFormat, error) {
+	for _, f := range outputFormats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	names := make([]string, len(outputFormats))
+	for i, f := range outputFormats {
+		names[i] = string(f)
+	}
+	return "", usageErrorf("usage: --output %v", strings.Join(names, "|"))
+}
+
+// Records written by listing commands in the machine-readable formats.
+// The json tags are the field names scripts rely on: add fields freely, but never rename or remove one.
+
+type feedRecord struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	SiteURL       *string    `json:"site_url"`
+	Description   *string    `json:"description"`
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+}
+
+type followRecord struct {
+	ID         uuid.UUID `json:"id"`
+	FeedID     uuid.UUID `json:"feed_id"`
+	FeedName   string    `json:"feed_name"`
+	FeedURL    string    `json:"feed_url"`
+	FollowedAt time.Time `json:"followed_at"`
+	Folder     *string   `json:"folder"`
+	// Title is the user's own title for the feed, else its name
+	Title string `json:"title"`
+}
+
+type postRecord struct {
+	ID          uuid.UUID `json:"id"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Description *string   `json:"description"`
+	PublishedAt time.Time `json:"published_at"`
+	FeedTitle   string    `json:"feed_title"`
+	Author      *string   `json:"author"`
+	Categories  []string  `json:"categories"`
+	// Tags are the ones the user's rules gave the post
+	Tags []string `json:"tags"`
+}
+
+type ruleRecord struct {
+	ID      uuid.UUID `json:"id"`
+	Field   string    `json:"field"`
+	Pattern string    `json:"pattern"`
+	Regex   bool      `json:"regex"`
+	Action  string    `json:"action"`
+	Tag     *string   `json:"tag"`
+	// FeedURL is null for rules applying to every followed feed
+	FeedURL   *string   `json:"feed_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type userRecord struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+	Admin   bool   `json:"admin"`
+}
+
+type whoamiRecord struct {
+	Name         string    `json:"name"`
+	Admin        bool      `json:"admin"`
+	Password     bool      `json:"password"`
+	CreatedAt    time.Time `json:"created_at"`
+	Follows      int64     `json:"follows"`
+	FeedsCreated int64     `json:"feeds_created"`
+	Unread       int64     `json:"unread"`
+}
+
+type configRecord struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+type profileRecord struct {
+	Name        string `json:"name"`
+	DBUrl       string `json:"db_url"`
+	CurrentUser string `json:"current_user"`
+	// Active is the profile used when none is given, InUse the one used by this command
+	Active bool `json:"active"`
+	InUse  bool `json:"in_use"`
+}
+
+type feedHistoryRecord struct {
+	FeedID    uuid.UUID `json:"feed_id"`
+	Event     string    `json:"event"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type migrationRecord struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+func newFeedRecord(feed database.Feed, createdBy string) feedRecord {
+	return feedRecord{
+		ID:            feed.ID,
+		Name:          feed.Name,
+		URL:           feed.Url,
+		SiteURL:       nullableString(feed.Link.String, feed.Link.Valid),
+		Description:   nullableString(feed.Description.String, feed.Description.Valid),
+		CreatedBy:     createdBy,
+		CreatedAt:     feed.CreatedAt,
+		UpdatedAt:     feed.UpdatedAt,
+		LastFetchedAt: nullableTime(feed.LastFetchedAt.Time, feed.LastFetchedAt.Valid),
+	}
+}
+
+func newFollowRecord(follow database.GetFeedFollowsForUserRow) followRecord {
+	return followRecord{
+		ID:         follow.ID,
+		FeedID:     follow.FeedID,
+		FeedName:   follow.FeedName,
+		FeedURL:    follow.FeedUrl,
+		FollowedAt: follow.CreatedAt,
+		Folder:     nullableString(follow.FolderName.String, follow.FolderName.Valid),
+		Title:      followTitle(follow),
+	}
+}
+
+func newPostRecord(post database.GetPostsForUserRow, tags []string) postRecord {
+	return postRecord{
+		ID:          post.ID,
+		FeedID:      post.FeedID,
+		Title:       post.Title,
+		URL:         post.Url,
+		Description: nullableString(post.Description.String, post.Description.Valid),
+		PublishedAt: post.PublishedAt,
+		FeedTitle:   post.FeedTitle,
+		Author:      nullableString(post.Author.String, post.Author.Valid),
+		Categories:  emptyIfNil(splitCategories(post.Categories)),
+		Tags:        emptyIfNil(tags),
+	}
+}
+
+func newRuleRecord(rule database.GetRulesForUserRow) ruleRecord {
+	return ruleRecord{
+		ID:        rule.ID,
+		Field:     rule.Field,
+		Pattern:   rule.Pattern,
+		Regex:     rule.Regex,
+		Action:    rule.Action,
+		Tag:       nullableString(rule.Tag.String, rule.Tag.Valid),
+		FeedURL:   nullableString(rule.FeedUrl.String, rule.FeedUrl.Valid),
+		CreatedAt: rule.CreatedAt,
+	}
+}
+
+func newFeedHistoryRecord(h database.FeedHistory) feedHistoryRecord {
+	return feedHistoryRecord{
+		FeedID:    h.FeedID,
+		Event:     h.Event,
+		OldValue:  nullableString(h.OldValue.String, h.OldValue.Valid),
+		NewValue:  nullableString(h.NewValue.String, h.NewValue.Valid),
+		CreatedAt: h.CreatedAt,
+	}
+}
+
+func newMigrationRecord(st migrate.Status) migrationRecord {
+	return migrationRecord{
+		Version:   st.Version,
+		Name:      st.Name,
+		Applied:   st.Applied,
+		AppliedAt: nullableTime(st.AppliedAt, st.Applied),
+	}
+}
+
+func nullableString(str string, valid bool) *string {
+	if !valid {
+		return nil
+	}
+	return &str
+}
+
+// emptyIfNil keeps lists encoded as [] rather than null
+func emptyIfNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
+
+func nullableTime(t time.Time, valid bool) *time.Time {
+	if !valid {
+		return nil
+	}
+	return &t
+}
+
+// writeRecords writes records to s.out in s.format, which must not be outputTable.
+// Every value is encoded the way encoding/json encodes it, so a field reads the same in every format.
+func writeRecords[T any](s *state, records []T) error {
+	if records == nil {
+		records = []T{}
+	}
+	switch s.format {
+	case outputJSON:
+		enc := json.NewEncoder(s.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case outputNDJSON:
+		enc := json.NewEncoder(s.out)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputCSV:
+		w := csv.NewWriter(s.out)
+		if err := w.Write(recordFields(reflect.TypeFor[T]())); err != nil {
+			return err
+		}
+		for _, r := range records {
+			values, err := recordValues(r)
+			if err != nil {
+				return err
+			}
+			row := make([]string, len(values))
+			for i, v := range values {
+				row[i] = csvValue(v)
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	case outputYAML:
+		if len(records) == 0 {
+			_, err := fmt.Fprintln(s.out, "[]")
+			return err
+		}
+		fields := recordFields(reflect.TypeFor[T]())
+		for _, r := range records {
+			values, err := recordValues(r)
+			if err != nil {
+				return err
+			}
+			for i, v := range values {
+				prefix := "  "
+				if i == 0 {
+					prefix = "- "
+				}
+				// JSON scalars are valid YAML flow scalars
+				fmt.Fprintf(s.out, "%v%v: %s\n", prefix, fields[i], v)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("error: output format '%v' cannot be written as records", s.format)
+}
+
+// recordFields returns the json field names of a record struct, in declaration order
+func recordFields(t reflect.Type) []string {
+	fields := make([]string, t.NumField())
+	for i := range fields {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[i] = name
+	}
+	return fields
+}
+
+// recordValues returns the JSON encoding of each field of record, in declaration order
+func recordValues(record any) ([]json.RawMessage, error) {
+	v := reflect.ValueOf(record)
+	values := make([]json.RawMessage, v.NumField())
+	for i := range values {
+		data, err := json.Marshal(v.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		values[i] = data
+	}
+	return values, nil
+}
+
+// csvValue unquotes JSON strings and leaves nulls empty, other values keep their JSON form
+func csvValue(v json.RawMessage) string {
+	if bytes.Equal(v, []byte("null")) {
+		return ""
+	}
+	var str string
+	if json.Unmarshal(v, &str) == nil {
+		return str
+	}
+	return string(v)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestListingOutput(t *testing.T) {
+	tests := []struct {
+		golden string
+		format outputFormat
+		name   string
+		args   []string
+	}{
+		{"browse", outputTable, "browse", nil},
+		{"browse_limit", outputTable, "browse", []string{"10"}},
+		{"feeds", outputTable, "feeds", nil},
+		{"following", outputTable, "following", nil},
+		{"users", outputTable, "users", nil},
+		{"browse_json", outputJSON, "browse", []string{"10"}},
+		{"feeds_json", outputJSON, "feeds", nil},
+		{"feeds_ndjson", outputNDJSON, "feeds", nil},
+		{"feeds_csv", outputCSV, "feeds", nil},
+		{"feeds_yaml", outputYAML, "feeds", nil},
+		{"following_csv", outputCSV, "following", nil},
+		{"users_json", outputJSON, "users", nil},
+		{"users_yaml", outputYAML, "users", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			s := newTestState(t)
+			s.format = tt.format
+			seedFixture(t, s)
+			mustRun(t, s, tt.name, tt.args...)
+			assertGolden(t, tt.golden, s.out.(*bytes.Buffer).Bytes())
+			if errOut := s.errOut.(*bytes.Buffer).String(); errOut != "" {
+				t.Errorf("unexpected stderr output: %q", errOut)
+			}
+		})
+	}
+}
+
+func TestEmptyListings(t *testing.T) {
+	want := map[outputFormat]string{
+		outputJSON:   "[]\n",
+		outputNDJSON: "",
+		outputCSV:    "id,feed_id,feed_name,feed_url,followed_at,folder,title\n",
+		outputYAML:   "[]\n",
+	}
+	for format, out := range want {
+		s := newTestState(t)
+		s.format = format
+		mustRun(t, s, "register", "alice")
+		s.out.(*bytes.Buffer).Reset()
+		mustRun(t, s, "following")
+		if got := s.out.(*bytes.Buffer).String(); got != out {
+			t.Errorf("%v: got %q, want %q", format, got, out)
+		}
+	}
+}
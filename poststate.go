@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// setPostRead marks a post read by user, or unread again
+func setPostRead(s *state, user database.User, postID uuid.UUID, read bool) error {
+	err := s.db.SetPostRead(
+		context.Background(),
+		database.SetPostReadParams{
+			UserID: user.ID,
+			PostID: postID,
+			ReadAt: sql.NullTime{Time: time.Now(), Valid: read},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not update read state of post %v \n%v", postID, err)
+	}
+	return nil
+}
+
+// setPostStarred stars a post for user, or removes the star
+func setPostStarred(s *state, user database.User, postID uuid.UUID, starred bool) error {
+	err := s.db.SetPostStarred(
+		context.Background(),
+		database.SetPostStarredParams{
+			UserID:    user.ID,
+			PostID:    postID,
+			StarredAt: sql.NullTime{Time: time.Now(), Valid: starred},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not update star of post %v \n%v", postID, err)
+	}
+	return nil
+}
+
+// setPostHidden hides a post from user, or shows it again
+func setPostHidden(s *state, user database.User, postID uuid.UUID, hidden bool) error {
+	err := s.db.SetPostHidden(
+		context.Background(),
+		database.SetPostHiddenParams{
+			UserID:   user.ID,
+			PostID:   postID,
+			HiddenAt: sql.NullTime{Time: time.Now(), Valid: hidden},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not update hidden state of post %v \n%v", postID, err)
+	}
+	return nil
+}
+
+// postFromArgs looks up the post whose id is the first argument
+func postFromArgs(s *state, cmd command) (database.Post, error) {
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return database.Post{}, usageErrorf("error: '%v' is not a post id \nusage: gator %v <post id>", cmd.args[0], cmd.name)
+	}
+	post, err := s.db.GetPost(context.Background(), id)
+	if err != nil {
+		return database.Post{}, fmt.Errorf("error: post %v not found", id)
+	}
+	return post, nil
+}
+
+// handlerPostState returns the handler of a 'post' subcommand setting read, starred or hidden to value
+func handlerPostState(setter func(*state, database.User, uuid.UUID, bool) error, value bool, done string) func(*state, command, database.User) error {
+	return func(s *state, cmd command, user database.User) error {
+		post, err := postFromArgs(s, cmd)
+		if err != nil {
+			return err
+		}
+		err = setter(s, user, post.ID, value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "%v '%v'\n", done, post.Title)
+		return nil
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestPostState(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	id := "00000000-0000-0000-0000-000000000002"
+	mustRun(t, s, "post", "read", id)
+	mustRun(t, s, "post", "star", id)
+	mustRun(t, s, "post", "star", id)
+	mustRun(t, s, "post", "unread", id)
+	user, _ := s.db.GetUser(context.Background(), "alice")
+	entries, err := s.db.GetPostEntries(context.Background(), database.GetPostEntriesParams{UserID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		want := e.ID.String() == id
+		if e.StarredAt.Valid != want || e.ReadAt.Valid {
+			t.Errorf("%v: starred %v read %v, want starred %v and unread", e.Title, e.StarredAt.Valid, e.ReadAt.Valid, want)
+		}
+	}
+	if err := run(t, s, "post", "read", "nope"); exitCode(err) != exitUsage {
+		t.Errorf("bad id: got %v, want a usage error", err)
+	}
+	if err := run(t, s, "post", "read", uuid.NewString()); exitCode(err) != exitError {
+		t.Errorf("unknown post: got %v, want an error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
+)
+
+func handlerProfileList(s *state, cmd command) error {
+	names := s.cfg.Profiles()
+	records := make([]profileRecord, len(names))
+	for i, name := range names {
+		p, err := s.cfg.SavedProfile(name)
+		if err != nil {
+			return err
+		}
+		records[i] = profileRecord{
+			Name:        name,
+			DBUrl:       redactDBUrl(p.DBUrl),
+			CurrentUser: p.CurrentUsername,
+			Active:      name == s.cfg.ActiveProfile(),
+			InUse:       name == s.cfg.ProfileName(),
+		}
+	}
+	if s.format != outputTable {
+		return writeRecords(s, records)
+	}
+	fmt.Fprintln(s.out, "============================PROFILES============================")
+	for _, r := range records {
+		switch {
+		case r.InUse:
+			fmt.Fprintf(s.out, "* %v (in use)\n", r.Name)
+		case r.Active:
+			fmt.Fprintf(s.out, "* %v (active)\n", r.Name)
+		default:
+			fmt.Fprintf(s.out, "* %v\n", r.Name)
+		}
+		fmt.Fprintf(s.out, "  db_url: %v\n", r.DBUrl)
+		fmt.Fprintf(s.out, "  user:   %v\n", r.CurrentUser)
+	}
+	fmt.Fprintln(s.out)
+	fmt.Fprintln(s.out, "================================================================")
+	return nil
+}
+
+func handlerProfileUse(s *state, cmd command) error {
+	err := s.cfg.UseProfile(cmd.args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Now using profile '%v'\n", cmd.args[0])
+	return nil
+}
+
+func handlerProfileAdd(s *state, cmd command) error {
+	name := cmd.args[0]
+	err := s.cfg.AddProfile(name, config.Profile{DBUrl: cmd.args[1]})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Added profile '%v'\n", name)
+	if !cmd.has("use") {
+		return nil
+	}
+	return handlerProfileUse(s, command{name: "use", args: []string{name}})
+}
+
+func handlerProfileRemove(s *state, cmd command) error {
+	err := s.cfg.RemoveProfile(cmd.args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Removed profile '%v'\n", cmd.args[0])
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
+)
+
+func TestProfiles(t *testing.T) {
+	s := newTestState(t)
+	writeConfig(t, `{"db_url":"postgres://localhost/gator","current_user_name":"alice"}`)
+	cfg, err := config.Read("")
+	if err != nil || cfg.ProfileName() != config.DefaultProfile || cfg.CurrentUsername != "alice" {
+		t.Fatalf("got %+v %v, want the flat file as the default profile", cfg, err)
+	}
+	s.cfg = cfg
+	mustRun(t, s, "profile", "add", "team", "sqlite:/tmp/team.db", "--use")
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "config", "set", "browse_limit", "5")
+	if team, err := config.Read(""); err != nil || team.ProfileName() != "team" || team.CurrentUsername != "bob" || team.BrowseLimit != "5" {
+		t.Errorf("got %+v %v, want the team profile saved in use", team, err)
+	}
+	if def, err := config.Read(config.DefaultProfile); err != nil || def.CurrentUsername != "alice" || def.BrowseLimit != "" {
+		t.Errorf("got %+v %v, want the default profile left as it was", def, err)
+	}
+
+	s.out = &bytes.Buffer{}
+	s.format = outputCSV
+	mustRun(t, s, "profile", "list")
+	wantList := "name,db_url,current_user,active,in_use\n" +
+		"default,postgres://localhost/gator,alice,false,false\n" +
+		"team,sqlite:/tmp/team.db,bob,true,true\n"
+	if got := s.out.(*bytes.Buffer).String(); got != wantList {
+		t.Errorf("got:\n%v\nwant:\n%v", got, wantList)
+	}
+
+	if err := run(t, s, "profile", "rm", "team"); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("got %v, want an error removing the profile in use", err)
+	}
+	if err := run(t, s, "profile", "rm", "default"); err == nil {
+		t.Error("removed the default profile")
+	}
+	if err := run(t, s, "config", "set", "output", "xml"); exitCode(err) != exitUsage {
+		t.Errorf("got %v, want a usage error for a bad output", err)
+	}
+	mustRun(t, s, "profile", "use", "default")
+	if s.cfg.CurrentUsername != "alice" || s.cfg.BrowseLimit != "" {
+		t.Errorf("settings of the default profile not loaded: %+v", s.cfg.Profile)
+	}
+	mustRun(t, s, "profile", "rm", "team")
+	if _, err := config.Read("team"); err == nil {
+		t.Error("removed profile still in the config file")
+	}
+}
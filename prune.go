@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// defaultUnreadGraceDays is how long prune spares unread posts when unread_grace_days is not set
+const defaultUnreadGraceDays = 7
+
+// retention is how many days and how many of the newest posts of a feed prune keeps, 0 for no limit
+type retention struct {
+	days  int
+	posts int
+}
+
+// configNumber reads the setting named key as a number, 0 when it is not set
+func configNumber(s *state, key string) (int, error) {
+	value, err := s.cfg.Get(key)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("error: %v '%v' of profile '%v' is not a number", key, value, s.cfg.ProfileName())
+	}
+	return n, nil
+}
+
+// configRetention returns the keep_days and keep_posts of the profile
+func configRetention(s *state) (retention, error) {
+	days, err := configNumber(s, config.KeyKeepDays)
+	if err != nil {
+		return retention{}, err
+	}
+	posts, err := configNumber(s, config.KeyKeepPosts)
+	if err != nil {
+		return retention{}, err
+	}
+	return retention{days: days, posts: posts}, nil
+}
+
+// with applies the settings a feed has of its own over r
+func (r retention) with(fr database.FeedRetention) retention {
+	if fr.KeepDays.Valid {
+		r.days = int(fr.KeepDays.Int32)
+	}
+	if fr.KeepPosts.Valid {
+		r.posts = int(fr.KeepPosts.Int32)
+	}
+	return r
+}
+
+func (r retention) String() string {
+	switch {
+	case r.days > 0 && r.posts > 0:
+		return fmt.Sprintf("%v day(s), at most %v post(s)", r.days, r.posts)
+	case r.days > 0:
+		return fmt.Sprintf("%v day(s)", r.days)
+	case r.posts > 0:
+		return fmt.Sprintf("at most %v post(s)", r.posts)
+	}
+	return "every post"
+}
+
+// feedRetentionOf returns the retention of the feed with id, its own settings applied over the profile's
+func feedRetentionOf(s *state, feedID uuid.UUID) (retention, error) {
+	r, err := configRetention(s)
+	if err != nil {
+		return retention{}, err
+	}
+	fr, err := s.db.GetFeedRetention(context.Background(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, nil
+	}
+	if err != nil {
+		return retention{}, fmt.Errorf("error: could not retreive retention settings \n%v", err)
+	}
+	return r.with(fr), nil
+}
+
+// feedPrune is a feed prune goes through, with the retention it prunes the feed to
+type feedPrune struct {
+	feed      database.Feed
+	retention retention
+	arg       database.PrunePostsParams
+}
+
+// prunePosts deletes the posts past the retention of their feed, or only lists them when dryRun is set.
+// Starred posts are always kept, and so are unread posts saved within the unread grace period.
+func prunePosts(s *state, dryRun bool) error {
+	global, err := configRetention(s)
+	if err != nil {
+		return err
+	}
+	grace := defaultUnreadGraceDays
+	if value, _ := s.cfg.Get(config.KeyUnreadGraceDays); value != "" {
+		grace, err = configNumber(s, config.KeyUnreadGraceDays)
+		if err != nil {
+			return err
+		}
+	}
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("error: could not retreive feeds \n%v", err)
+	}
+	settings, err := s.db.GetFeedRetentions(context.Background())
+	if err != nil {
+		return fmt.Errorf("error: could not retreive retention settings \n%v", err)
+	}
+	own := make(map[uuid.UUID]database.FeedRetention, len(settings))
+	for _, fr := range settings {
+		own[fr.FeedID] = fr
+	}
+
+	now := time.Now()
+	var pruning []feedPrune
+	for _, feed := range feeds {
+		r := global.with(own[feed.ID])
+		if r.days == 0 && r.posts == 0 {
+			continue
+		}
+		arg := database.PrunePostsParams{
+			FeedID:      feed.ID,
+			UnreadSince: now.AddDate(0, 0, -grace),
+		}
+		if r.days > 0 {
+			arg.PublishedBefore = sql.NullTime{Time: now.AddDate(0, 0, -r.days), Valid: true}
+		}
+		if r.posts > 0 {
+			arg.KeepPosts = sql.NullInt64{Int64: int64(r.posts), Valid: true}
+		}
+		pruning = append(pruning, feedPrune{feed: feed, retention: r, arg: arg})
+	}
+
+	verb := "Deleted"
+	if dryRun {
+		verb = "Would delete"
+	}
+	total, pruned := int64(0), 0
+	report := func(fp feedPrune, count int64) {
+		if count == 0 {
+			return
+		}
+		fmt.Fprintf(s.out, "%v %v post(s) of '%v', which keeps %v\n", verb, count, fp.feed.Name, fp.retention)
+		total += count
+		pruned++
+	}
+	fmt.Fprintln(s.out, "=============================PRUNE==============================")
+	if dryRun {
+		for _, fp := range pruning {
+			posts, err := s.db.GetPostsToPrune(context.Background(), database.GetPostsToPruneParams(fp.arg))
+			if err != nil {
+				return fmt.Errorf("error: could not retreive posts of '%v' \n%v", fp.feed.Name, err)
+			}
+			for _, post := range posts {
+				fmt.Fprintf(s.out, "  %v (%v)\n", post.Title, post.PublishedAt.Format(time.DateOnly))
+			}
+			report(fp, int64(len(posts)))
+		}
+	} else {
+		// One delete per feed, all committed together so an error leaves every feed as it was
+		counts := make([]int64, len(pruning))
+		err = inTx(s, func(tx *state) error {
+			var err error
+			for i, fp := range pruning {
+				counts[i], err = tx.db.PrunePosts(context.Background(), fp.arg)
+				if err != nil {
+					return fmt.Errorf("error: could not delete posts of '%v' \n%v", fp.feed.Name, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for i, fp := range pruning {
+			report(fp, counts[i])
+		}
+	}
+	fmt.Fprintf(s.out, "%v %v post(s) from %v feed(s)\n", verb, total, pruned)
+	fmt.Fprintln(s.out, "================================================================")
+	return nil
+}
+
+func handlerPrune(s *state, cmd command, user database.User) error {
+	return prunePosts(s, cmd.has("dry-run"))
+}
+
+// retentionFlag reads a --days or --posts flag, keeping current when it was not given
+func retentionFlag(cmd command, name string, current sql.NullInt32) (sql.NullInt32, error) {
+	if !cmd.has(name) {
+		return current, nil
+	}
+	n, err := strconv.Atoi(cmd.flag(name))
+	if err != nil || n < 0 {
+		return sql.NullInt32{}, usageErrorf("usage: gator feed retention <url> [--days n] [--posts n] [--clear], 0 keeps every post")
+	}
+	return sql.NullInt32{Int32: int32(n), Valid: true}, nil
+}
+
+// handlerFeedRetention shows the retention of a feed, or changes the settings the feed has of its own
+func handlerFeedRetention(s *state, cmd command, user database.User) error {
+	change := cmd.has("clear") || cmd.has("days") || cmd.has("posts")
+	var feed database.Feed
+	var err error
+	if change {
+		feed, err = managedFeed(s, user, cmd.args[0])
+	} else {
+		feed, err = s.db.GetFeedByURL(context.Background(), cmd.args[0])
+		if err != nil {
+			err = fmt.Errorf("error: feed '%v' not registered, use 'gator feeds' to see existing feeds", cmd.args[0])
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if cmd.has("clear") {
+		err = s.db.DeleteFeedRetention(context.Background(), feed.ID)
+		if err != nil {
+			return fmt.Errorf("error: could not clear retention of '%v' \n%v", feed.Name, err)
+		}
+	} else if change {
+		fr, err := s.db.GetFeedRetention(context.Background(), feed.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error: could not retreive retention of '%v' \n%v", feed.Name, err)
+		}
+		days, err := retentionFlag(cmd, "days", fr.KeepDays)
+		if err != nil {
+			return err
+		}
+		posts, err := retentionFlag(cmd, "posts", fr.KeepPosts)
+		if err != nil {
+			return err
+		}
+		err = s.db.SetFeedRetention(
+			context.Background(),
+			database.SetFeedRetentionParams{
+				FeedID:    feed.ID,
+				UpdatedAt: time.Now(),
+				KeepDays:  days,
+				KeepPosts: posts,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("error: could not set retention of '%v' \n%v", feed.Name, err)
+		}
+	}
+	r, err := feedRetentionOf(s, feed.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "'%v' keeps %v\n", feed.Name, r)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestPrune(t *testing.T) {
+	for name, newState := range testBackends {
+		t.Run(name, func(t *testing.T) {
+			testPrune(t, newState(t))
+		})
+	}
+}
+
+func testPrune(t *testing.T, s *state) {
+	seedFixture(t, s)
+	ctx := context.Background()
+	feedID := uuid.MustParse("00000000-0000-0000-0000-0000000000f1")
+	at := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	countPosts := func() int64 {
+		t.Helper()
+		n, err := s.db.CountPostsForFeed(ctx, feedID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	loginWithPassword(t, s, "alice")
+	mustRun(t, s, "prune")
+	if countPosts() != 3 {
+		t.Fatal("prune without retention settings should keep every post")
+	}
+
+	// First is starred and so kept, Second and Third are unread but saved long ago
+	s.cfg.KeepPosts = "1"
+	mustRun(t, s, "post", "star", "00000000-0000-0000-0000-000000000001")
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "prune", "--dry-run")
+	if got := s.out.(*bytes.Buffer).String(); !strings.Contains(got, "  Second (2025-02-27)\n") || !strings.Contains(got, "Would delete 1 post(s) from 1 feed(s)") {
+		t.Errorf("unexpected dry run: %q", got)
+	}
+	if countPosts() != 3 {
+		t.Fatal("a dry run should not delete posts")
+	}
+
+	// The feed's own settings win over the config, until cleared
+	mustRun(t, s, "feed", "retention", "https://blog.example.com/rss.xml", "--posts", "0")
+	mustRun(t, s, "prune")
+	if countPosts() != 3 {
+		t.Fatal("--posts 0 should keep every post of the feed")
+	}
+	mustRun(t, s, "feed", "retention", "https://blog.example.com/rss.xml", "--clear")
+
+	// A post fetched just now is saved and stays while unread, however old it is
+	fresh := &RSSFeed{}
+	fresh.Channel.Item = []RSSItem{{
+		Title:   "Fresh",
+		Link:    "https://blog.example.com/4",
+		PubDate: at.AddDate(0, 0, -10).Format(time.RFC1123Z),
+	}}
+	if err := savePosts(s, feedID, fresh); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, s, "prune")
+	posts, _ := s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: uuid.MustParse("00000000-0000-0000-0000-00000000a11c"), Limit: 10})
+	titles := []string{}
+	for _, post := range posts {
+		titles = append(titles, post.Title)
+	}
+	if fmt.Sprint(titles) != "[Third First Fresh]" {
+		t.Errorf("got posts %v, want the newest, the starred and the unread one kept", titles)
+	}
+	mustRun(t, s, "post", "read", posts[2].ID.String())
+	mustRun(t, s, "prune")
+	if countPosts() != 2 {
+		t.Errorf("got %v posts, want the read post pruned", countPosts())
+	}
+
+	s.cfg.KeepPosts = "2"
+
+	mustRun(t, s, "login", "bob")
+	if err := run(t, s, "prune"); err == nil || !strings.Contains(err.Error(), "only admins") {
+		t.Errorf("got %v, want prune to need an admin", err)
+	}
+	if err := run(t, s, "feed", "retention", "https://blog.example.com/rss.xml", "--days", "3"); err == nil {
+		t.Error("only the user who added a feed should set its retention")
+	}
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "feed", "retention", "https://blog.example.com/rss.xml")
+	if got := s.out.(*bytes.Buffer).String(); got != "'Test Blog' keeps at most 2 post(s)\n" {
+		t.Errorf("got %q", got)
+	}
+}
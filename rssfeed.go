@@ -1,5 +1,10 @@
 package main
 
+import (
+	"encoding/xml"
+	"strings"
+)
+
 type RSSFeed struct {
 	Channel struct {
 		Title       string    `xml:"title"`
@@ -14,4 +19,144 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	Author      string `xml:"author"`
+	// Creator is Dublin Core's dc:creator, which many feeds use instead of author
+	Creator    string   `xml:"creator"`
+	Categories []string `xml:"category"`
+}
+
+// author returns who wrote the item, empty if the feed does not say
+func (i RSSItem) author() string {
+	if i.Author != "" {
+		return i.Author
+	}
+	return i.Creator
+}
+
+type AtomFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type AtomEntry struct {
+	Title      string         `xml:"title"`
+	Links      []AtomLink     `xml:"link"`
+	Summary    string         `xml:"summary"`
+	Content    string         `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []string       `xml:"author>name"`
+	Categories []AtomCategory `xml:"category"`
+}
+
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	Title         string `json:"title"`
+	URL           string `json:"url"`
+	Summary       string `json:"summary"`
+	ContentText   string `json:"content_text"`
+	ContentHTML   string `json:"content_html"`
+	DatePublished string `json:"date_published"`
+	// Author is from version 1.0 of the spec, which 1.1 replaced with Authors
+	Author  JSONFeedAuthor   `json:"author"`
+	Authors []JSONFeedAuthor `json:"authors"`
+	Tags    []string         `json:"tags"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// alternateLink returns the link an Atom element points readers to, which is the
+// "alternate" link or, failing that, the first link without a rel.
+func alternateLink(links []AtomLink) string {
+	for _, l := range links {
+		if l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	for _, l := range links {
+		if l.Rel == "" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func (a *AtomFeed) toRSS() *RSSFeed {
+	feed := RSSFeed{}
+	feed.Channel.Title = a.Title
+	feed.Channel.Link = alternateLink(a.Links)
+	feed.Channel.Description = a.Subtitle
+	for _, e := range a.Entries {
+		item := RSSItem{
+			Title:       e.Title,
+			Link:        alternateLink(e.Links),
+			Description: e.Summary,
+			PubDate:     e.Published,
+		}
+		if item.Description == "" {
+			item.Description = e.Content
+		}
+		if item.PubDate == "" {
+			item.PubDate = e.Updated
+		}
+		item.Author = strings.Join(e.Authors, ", ")
+		for _, c := range e.Categories {
+			item.Categories = append(item.Categories, c.Term)
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+	return &feed
+}
+
+func (j *JSONFeed) toRSS() *RSSFeed {
+	feed := RSSFeed{}
+	feed.Channel.Title = j.Title
+	feed.Channel.Link = j.HomePageURL
+	feed.Channel.Description = j.Description
+	for _, i := range j.Items {
+		item := RSSItem{
+			Title:       i.Title,
+			Link:        i.URL,
+			Description: i.Summary,
+			PubDate:     i.DatePublished,
+			Author:      i.Author.Name,
+			Categories:  i.Tags,
+		}
+		if item.Description == "" {
+			item.Description = i.ContentText
+		}
+		if item.Description == "" {
+			item.Description = i.ContentHTML
+		}
+		if len(i.Authors) > 0 {
+			names := make([]string, len(i.Authors))
+			for n, a := range i.Authors {
+				names[n] = a.Name
+			}
+			item.Author = strings.Join(names, ", ")
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+	return &feed
 }
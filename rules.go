@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// The parts of a post a rule can match
+const (
+	ruleFieldTitle       = "title"
+	ruleFieldDescription = "description"
+	ruleFieldAuthor      = "author"
+	ruleFieldCategory    = "category"
+)
+
+var ruleFields = []string{ruleFieldTitle, ruleFieldDescription, ruleFieldAuthor, ruleFieldCategory}
+
+// What a rule does to the posts it matches, for the user who made it
+const (
+	ruleActionHide = "hide"
+	ruleActionRead = "read"
+	ruleActionStar = "star"
+	ruleActionTag  = "tag"
+)
+
+var ruleActions = []string{ruleActionHide, ruleActionRead, ruleActionStar, ruleActionTag}
+
+// joinCategories stores the categories of an item one per line, so a rule can match each on its own
+func joinCategories(categories []string) sql.NullString {
+	var kept []string
+	for _, c := range categories {
+		if c = strings.TrimSpace(c); c != "" {
+			kept = append(kept, c)
+		}
+	}
+	return nullString(strings.Join(kept, "\n"))
+}
+
+// splitCategories returns the categories of a post, stored by joinCategories
+func splitCategories(categories sql.NullString) []string {
+	if categories.String == "" {
+		return nil
+	}
+	return strings.Split(categories.String, "\n")
+}
+
+// compiledRule is a rule with its regular expression, if it has one, compiled
+type compiledRule struct {
+	database.Rule
+	re *regexp.Regexp
+}
+
+func compileRule(rule database.Rule) (compiledRule, error) {
+	c := compiledRule{Rule: rule}
+	if rule.Regex {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("error: '%v' is not a regular expression \n%v", rule.Pattern, err)
+		}
+		c.re = re
+	}
+	return c, nil
+}
+
+// matchText reports whether text matches the rule's regular expression or,
+// for plain rules, contains its pattern ignoring case
+func (r compiledRule) matchText(text string) bool {
+	if r.re != nil {
+		return r.re.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(r.Pattern))
+}
+
+// matches reports whether post is in the rule's feed, if it has one, and matches its pattern
+func (r compiledRule) matches(post database.Post) bool {
+	if r.FeedID.Valid && r.FeedID.UUID != post.FeedID {
+		return false
+	}
+	switch r.Field {
+	case ruleFieldTitle:
+		return r.matchText(post.Title)
+	case ruleFieldDescription:
+		return r.matchText(post.Description.String)
+	case ruleFieldAuthor:
+		return r.matchText(post.Author.String)
+	case ruleFieldCategory:
+		return slices.ContainsFunc(splitCategories(post.Categories), r.matchText)
+	}
+	return false
+}
+
+// apply does the rule's action to post for the user who made the rule
+func (r compiledRule) apply(s *state, post database.Post) error {
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+	var err error
+	switch r.Action {
+	case ruleActionHide:
+		err = s.db.SetPostHidden(
+			context.Background(),
+			database.SetPostHiddenParams{UserID: r.UserID, PostID: post.ID, HiddenAt: now},
+		)
+	case ruleActionRead:
+		err = s.db.SetPostRead(
+			context.Background(),
+			database.SetPostReadParams{UserID: r.UserID, PostID: post.ID, ReadAt: now},
+		)
+	case ruleActionStar:
+		err = s.db.SetPostStarred(
+			context.Background(),
+			database.SetPostStarredParams{UserID: r.UserID, PostID: post.ID, StarredAt: now},
+		)
+	case ruleActionTag:
+		err = s.db.TagPost(
+			context.Background(),
+			database.TagPostParams{UserID: r.UserID, PostID: post.ID, Tag: r.Tag.String},
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("error: could not apply rule '%v' to '%v' \n%v", r, post.Title, err)
+	}
+	return nil
+}
+
+func (r compiledRule) String() string {
+	action := r.Action
+	if r.Action == ruleActionTag {
+		action = fmt.Sprintf("tag '%v'", r.Tag.String)
+	}
+	if r.Regex {
+		return fmt.Sprintf("%v if %v matches /%v/", action, r.Field, r.Pattern)
+	}
+	return fmt.Sprintf("%v if %v contains '%v'", action, r.Field, r.Pattern)
+}
+
+// compileRules compiles rules, leaving out any whose regular expression no longer compiles
+func compileRules(s *state, rules []database.Rule) []compiledRule {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compileRule(rule)
+		if err != nil {
+			fmt.Fprintf(s.errOut, "Warning: skipping rule %v \n%v\n", rule.ID, err)
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+// feedRules returns the rules that apply to new posts of the feed with id, those of the users following it
+func feedRules(s *state, feedID uuid.UUID) ([]compiledRule, error) {
+	rules, err := s.db.GetRulesForFeed(context.Background(), feedID)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not retreive rules \n%v", err)
+	}
+	return compileRules(s, rules), nil
+}
+
+// applyRules applies each of rules matching post, returning those that did
+func applyRules(s *state, rules []compiledRule, post database.Post) ([]compiledRule, error) {
+	var applied []compiledRule
+	for _, rule := range rules {
+		if !rule.matches(post) {
+			continue
+		}
+		err := rule.apply(s, post)
+		if err != nil {
+			return applied, err
+		}
+		applied = append(applied, rule)
+	}
+	return applied, nil
+}
+
+// userRules returns the rules user made, with the url of the feed each is limited to
+func userRules(s *state, user database.User) ([]database.GetRulesForUserRow, error) {
+	rows, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not retreive rules of %v \n%v", user.Name, err)
+	}
+	return rows, nil
+}
+
+// ruleOf returns the rule of a GetRulesForUser row
+func ruleOf(row database.GetRulesForUserRow) database.Rule {
+	return database.Rule{
+		ID:        row.ID,
+		CreatedAt: row.CreatedAt,
+		UserID:    row.UserID,
+		FeedID:    row.FeedID,
+		Field:     row.Field,
+		Pattern:   row.Pattern,
+		Regex:     row.Regex,
+		Action:    row.Action,
+		Tag:       row.Tag,
+	}
+}
+
+func handlerRulesAdd(s *state, cmd command, user database.User) error {
+	usage := "usage: gator rules add <field> <pattern> <action> [<tag>] [--regex] [--feed url]"
+	field, pattern, action := cmd.args[0], cmd.args[1], cmd.args[2]
+	if !slices.Contains(ruleFields, field) {
+		return usageErrorf("error: rules match %v, not '%v' \n%v", strings.Join(ruleFields, ", "), field, usage)
+	}
+	if !slices.Contains(ruleActions, action) {
+		return usageErrorf("error: rules can %v, not '%v' \n%v", strings.Join(ruleActions, ", "), action, usage)
+	}
+	tag := ""
+	if len(cmd.args) == 4 {
+		tag = strings.TrimSpace(cmd.args[3])
+	}
+	if (action == ruleActionTag) != (tag != "") {
+		return usageErrorf("error: the tag action needs a tag, and the other actions take none \n%v", usage)
+	}
+	if pattern == "" {
+		return usageErrorf("error: the pattern cannot be empty \n%v", usage)
+	}
+	feedID := uuid.NullUUID{}
+	if cmd.has("feed") {
+		feed, err := s.db.GetFeedByURL(context.Background(), cmd.flag("feed"))
+		if err != nil {
+			return fmt.Errorf("error: feed '%v' not registered, use 'gator feeds' to see existing feeds", cmd.flag("feed"))
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	rule, err := compileRule(database.Rule{Pattern: pattern, Regex: cmd.has("regex")})
+	if err != nil {
+		return err
+	}
+	row, err := s.db.CreateRule(
+		context.Background(),
+		database.CreateRuleParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    feedID,
+			Field:     field,
+			Pattern:   pattern,
+			Regex:     rule.Regex,
+			Action:    action,
+			Tag:       nullString(tag),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not add rule \n%v", err)
+	}
+	rule.Rule = row
+	fmt.Fprintf(s.out, "Added rule %v: %v\n", row.ID, rule)
+	fmt.Fprintln(s.out, "It applies to posts saved from now on, use 'gator rules apply' for the posts already saved")
+	return nil
+}
+
+func handlerRulesList(s *state, cmd command, user database.User) error {
+	rows, err := userRules(s, user)
+	if err != nil {
+		return err
+	}
+	if s.format != outputTable {
+		records := make([]ruleRecord, len(rows))
+		for i, row := range rows {
+			records[i] = newRuleRecord(row)
+		}
+		return writeRecords(s, records)
+	}
+	fmt.Fprintln(s.out, "==============================RULES=============================")
+	for _, row := range rows {
+		rule := compiledRule{Rule: ruleOf(row)}
+		fmt.Fprintf(s.out, "ID:    %v\n", row.ID)
+		fmt.Fprintf(s.out, "Rule:  %v\n", rule)
+		if row.FeedUrl.Valid {
+			fmt.Fprintf(s.out, "Feed:  %v\n", row.FeedUrl.String)
+		} else {
+			fmt.Fprintln(s.out, "Feed:  every feed you follow")
+		}
+		fmt.Fprintln(s.out, "================================================================")
+	}
+	return nil
+}
+
+func handlerRulesRemove(s *state, cmd command, user database.User) error {
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return usageErrorf("error: '%v' is not a rule id \nusage: gator rules rm <rule id>", cmd.args[0])
+	}
+	deleted, err := s.db.DeleteRule(
+		context.Background(),
+		database.DeleteRuleParams{
+			ID:     id,
+			UserID: user.ID,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not delete rule %v \n%v", id, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("error: you have no rule %v, use 'gator rules list' to see your rules", id)
+	}
+	fmt.Fprintf(s.out, "Deleted rule %v, the posts it matched keep what it did to them\n", id)
+	return nil
+}
+
+// handlerRulesApply applies the user's rules to the posts already saved from the feeds they follow
+func handlerRulesApply(s *state, cmd command, user database.User) error {
+	rows, err := userRules(s, user)
+	if err != nil {
+		return err
+	}
+	rules := make([]database.Rule, len(rows))
+	for i, row := range rows {
+		rules[i] = ruleOf(row)
+	}
+	compiled := compileRules(s, rules)
+	posts, err := s.db.GetPostsOfFollowedFeeds(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not retreive posts \n%v", err)
+	}
+	counts := make(map[uuid.UUID]int, len(compiled))
+	matched := 0
+	for _, post := range posts {
+		applied, err := applyRules(s, compiled, post)
+		if err != nil {
+			return err
+		}
+		for _, rule := range applied {
+			counts[rule.ID]++
+		}
+		if len(applied) > 0 {
+			matched++
+		}
+	}
+	for _, rule := range compiled {
+		fmt.Fprintf(s.out, "%v: %v post(s)\n", rule, counts[rule.ID])
+	}
+	fmt.Fprintf(s.out, "Applied %v rule(s) to %v of %v post(s)\n", len(compiled), matched, len(posts))
+	return nil
+}
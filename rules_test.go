@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+)
+
+func TestRules(t *testing.T) {
+	s := newTestState(t)
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel>
+<title>Tagged</title>
+<item><title>SPONSORED: a gopher plush</title><link>https://tagged.example.com/1</link><description>buy</description><category>Go</category></item>
+<item><title>Go 1.30 is out</title><link>https://tagged.example.com/2</link><description>release notes</description><category>news</category><category> go </category></item>
+<item><title>Weekly</title><link>https://tagged.example.com/3</link><description>one week</description><dc:creator>Bob</dc:creator><category>Gopher</category></item>
+</channel></rss>`)
+	}))
+	defer srv.Close()
+	url := srv.URL + "/rss.xml"
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", url)
+	mustRun(t, s, "rules", "add", "title", "sponsored", "hide")
+	mustRun(t, s, "rules", "add", "category", "^go$", "tag", "golang", "--regex")
+	mustRun(t, s, "rules", "add", "author", "bob", "star", "--feed", url)
+	for _, args := range [][]string{
+		{"add", "body", "x", "hide"},
+		{"add", "title", "x", "delete"},
+		{"add", "title", "x", "tag"},
+		{"add", "title", "x", "hide", "extra"},
+		{"add", "title", "(", "hide", "--regex"},
+		{"rm", "nope"},
+	} {
+		if err := run(t, s, "rules", args...); err == nil {
+			t.Errorf("rules %v should fail", args)
+		}
+	}
+	if err := scrapeFeeds(s); err != nil {
+		t.Fatal(err)
+	}
+
+	alice, _ := s.db.GetUser(ctx, "alice")
+	titles := func(hidden bool, tag string) string {
+		t.Helper()
+		posts, err := s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: alice.ID, Hidden: hidden, Tag: nullString(tag), Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, p := range posts {
+			titles = append(titles, p.Title)
+		}
+		slices.Sort(titles)
+		return strings.Join(titles, ", ")
+	}
+	if got := titles(false, ""); got != "Go 1.30 is out, Weekly" {
+		t.Errorf("browse shows %q, want the sponsored post hidden", got)
+	}
+	if got := titles(true, ""); got != "SPONSORED: a gopher plush" {
+		t.Errorf("hidden posts are %q", got)
+	}
+	if got := titles(false, "golang"); got != "Go 1.30 is out" {
+		t.Errorf("posts tagged golang are %q, want the one in category go", got)
+	}
+	entries, _ := s.db.GetPostEntries(ctx, database.GetPostEntriesParams{UserID: alice.ID, Limit: 10})
+	for _, e := range entries {
+		if e.StarredAt.Valid != (e.Title == "Weekly") {
+			t.Errorf("%v: starred %v, want only the post by Bob starred", e.Title, e.StarredAt.Valid)
+		}
+	}
+	s.format = outputNDJSON
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "browse", "1", "--tag", "golang")
+	if got := s.out.(*bytes.Buffer).String(); !strings.Contains(got, `"categories":["news","go"],"tags":["golang"]`) {
+		t.Errorf("browse does not show categories and tags: %q", got)
+	}
+
+	// Rules belong to one user, and apply retroactively to posts already saved
+	mustRun(t, s, "register", "bob")
+	mustRun(t, s, "follow", url)
+	mustRun(t, s, "rules", "add", "description", "WEEK", "read")
+	s.format = outputTable
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "rules", "apply")
+	if got := s.out.(*bytes.Buffer).String(); !strings.Contains(got, "read if description contains 'WEEK': 1 post(s)\nApplied 1 rule(s) to 1 of 3 post(s)\n") {
+		t.Errorf("unexpected apply output: %q", got)
+	}
+	bob, _ := s.db.GetUser(ctx, "bob")
+	stats, _ := s.db.GetUserStats(ctx, bob.ID)
+	if stats.Unread != 2 {
+		t.Errorf("bob has %v unread posts, want 2", stats.Unread)
+	}
+	rules, _ := s.db.GetRulesForUser(ctx, bob.ID)
+	if len(rules) != 1 {
+		t.Fatalf("bob has %v rules, want 1", len(rules))
+	}
+	mustRun(t, s, "login", "alice")
+	if err := run(t, s, "rules", "rm", rules[0].ID.String()); err == nil {
+		t.Error("removing another user's rule should fail")
+	}
+	hidden, _ := s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: alice.ID, Hidden: true, Limit: 10})
+	mustRun(t, s, "post", "unhide", hidden[0].ID.String())
+	if got := titles(true, ""); got != "" {
+		t.Errorf("got %q hidden after unhide", got)
+	}
+}
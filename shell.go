@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	shellHistoryFile  = ".gator_history"
+	shellHistoryLimit = 1000
+)
+
+// errShellExit is returned by runShellLine when the user asks to leave the shell
+var errShellExit = errors.New("exit")
+
+// handlerShell reads commands from s.in until 'exit' or end of input, running each with the same
+// state and database pool. On a terminal it offers line editing, history and tab completion.
+func (c *commands) handlerShell(s *state, cmd command) error {
+	if f, ok := s.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return c.interactiveShell(s, f)
+	}
+	// Commands are read through readLine so that prompts of the commands read the lines after them
+	failed := 0
+	for {
+		line, err := s.readLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error: could not read commands \n%v", err)
+		}
+		err = c.runShellLine(s, line)
+		if errors.Is(err, errShellExit) {
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(s.errOut, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("error: %v command(s) failed", failed)
+	}
+	return nil
+}
+
+func (c *commands) interactiveShell(s *state, in *os.File) error {
+	fd := int(in.Fd())
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, s.out}, "")
+	t.History = loadShellHistory()
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return c.shellComplete(s, t, line, pos)
+	}
+	fmt.Fprintln(s.out, "gator shell: type 'help' to list commands and 'exit' or ctrl+d to leave")
+	for {
+		if width, height, err := term.GetSize(fd); err == nil {
+			t.SetSize(width, height)
+		}
+		t.SetPrompt(shellPrompt(s))
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("error: could not set up the terminal \n%v", err)
+		}
+		line, err := t.ReadLine()
+		term.Restore(fd, oldState)
+		if err == io.EOF {
+			fmt.Fprintln(s.out)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error: could not read command \n%v", err)
+		}
+		err = c.runShellLine(s, line)
+		if errors.Is(err, errShellExit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(s.errOut, err)
+		}
+	}
+}
+
+func shellPrompt(s *state) string {
+	if s.cfg.CurrentUsername == "" {
+		return "gator> "
+	}
+	return fmt.Sprintf("gator (%v)> ", s.cfg.CurrentUsername)
+}
+
+// runShellLine runs one line typed in the shell. Global flags such as --output apply to that line only.
+func (c *commands) runShellLine(s *state, line string) error {
+	words, err := splitLine(line)
+	if err != nil {
+		return usageErrorf("error: %v", err)
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	switch words[0] {
+	case "exit", "quit":
+		return errShellExit
+	case "shell":
+		return usageErrorf("error: already in a gator shell")
+	}
+	cmd, opts, err := c.cleanInput(append([]string{"gator"}, words...))
+	if err != nil {
+		return err
+	}
+	if opts.dbURL != "" || opts.user != "" || opts.profile != "" {
+		return usageErrorf("error: --profile, --db-url and --user apply to the whole shell, pass them to 'gator shell' or use login")
+	}
+	if opts.output != "" {
+		format, err := parseOutputFormat(opts.output)
+		if err != nil {
+			return err
+		}
+		defer func(format outputFormat) { s.format = format }(s.format)
+		s.format = format
+	}
+	return c.run(s, *cmd)
+}
+
+// splitLine splits a line into words like a shell does: on spaces, except within single
+// or double quotes, with backslash escaping the next character outside single quotes.
+func splitLine(line string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("line ends with a backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// quoteWord quotes word for splitLine when it holds spaces or quotes
+func quoteWord(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t'\"\\") {
+		return word
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(word) + `"`
+}
+
+// shellComplete completes the word before the cursor. When several values are possible it
+// completes their common prefix, or lists them if there is none to add.
+func (c *commands) shellComplete(s *state, out io.Writer, line string, pos int) (string, int, bool) {
+	head, tail := line[:pos], line[pos:]
+	words, err := splitLine(head)
+	if err != nil {
+		return "", 0, false
+	}
+	start := strings.LastIndexAny(head, " \t") + 1
+	if start == len(head) {
+		words = append(words, "")
+	}
+	current := words[len(words)-1]
+	completions := c.complete(s, words)
+	if len(completions) == 0 {
+		return "", 0, false
+	}
+	completed := ""
+	if len(completions) == 1 {
+		completed = quoteWord(completions[0].value) + " "
+	} else {
+		prefix := completions[0].value
+		for _, comp := range completions[1:] {
+			for !strings.HasPrefix(comp.value, prefix) {
+				prefix = prefix[:len(prefix)-1]
+			}
+		}
+		if len(prefix) > len(current) && strings.HasPrefix(prefix, current) {
+			completed = prefix
+		} else {
+			fmt.Fprintln(out)
+			for _, comp := range completions {
+				if comp.description == "" {
+					fmt.Fprintf(out, "  %v\n", comp.value)
+				} else {
+					fmt.Fprintf(out, "  %-30v %v\n", comp.value, comp.description)
+				}
+			}
+			return line, pos, true
+		}
+	}
+	head = head[:start] + completed
+	return head + tail, len(head), true
+}
+
+// shellHistory is the shell's line history, appended to a file in the home directory
+// so it carries over between sessions
+type shellHistory struct {
+	// entries are ordered oldest first
+	entries []string
+	path    string
+}
+
+func loadShellHistory() *shellHistory {
+	h := &shellHistory{}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return h
+	}
+	h.path = filepath.Join(home, shellHistoryFile)
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		return h
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > shellHistoryLimit {
+		h.entries = h.entries[len(h.entries)-shellHistoryLimit:]
+		os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600)
+	}
+	return h
+}
+
+func (h *shellHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > shellHistoryLimit {
+		h.entries = h.entries[1:]
+	}
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, entry)
+}
+
+func (h *shellHistory) Len() int {
+	return len(h.entries)
+}
+
+func (h *shellHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestShell(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	s.in = strings.NewReader(strings.Join([]string{
+		"users -o csv",
+		"",
+		"login bob",
+		"following",
+		"nope",
+		"feed rename https://quiet.example.com/feed 'Very Quiet'",
+		"login alice",
+		`feed rename https://quiet.example.com/feed "Quiet \"Blog\""`,
+		"exit",
+		"users",
+	}, "\n"))
+	err := newCommands().run(s, command{name: "shell"})
+	if err == nil || err.Error() != "error: 2 command(s) failed" {
+		t.Errorf("got %v, want 2 failed commands", err)
+	}
+	out := s.out.(*bytes.Buffer).String()
+	for _, want := range []string{"name,current,admin\nalice,true,true\nbob,false,false\n", "Now logged in as bob", "Renamed 'Quiet Blog' to 'Quiet \"Blog\"'"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%v", want, out)
+		}
+	}
+	if strings.Contains(out, "* alice") {
+		t.Errorf("commands after exit ran:\n%v", out)
+	}
+	if s.format != outputTable {
+		t.Errorf("--output leaked out of its line: %v", s.format)
+	}
+	errOut := s.errOut.(*bytes.Buffer).String()
+	for _, want := range []string{"command 'nope' does not exist", "only the user who added"} {
+		if !strings.Contains(errOut, want) {
+			t.Errorf("stderr is missing %q:\n%v", want, errOut)
+		}
+	}
+}
+
+func TestShellPasswordPrompts(t *testing.T) {
+	s := newTestState(t)
+	// Passwords answering prompts are the lines right after their command, never run as commands
+	s.in = strings.NewReader(strings.Join([]string{
+		"register carol",
+		"pw",
+		"pw",
+		"logout",
+		"login carol",
+		"wrong",
+		"login carol",
+		"pw",
+		"whoami",
+	}, "\n"))
+	err := newCommands().run(s, command{name: "shell"})
+	if err == nil || err.Error() != "error: 1 command(s) failed" {
+		t.Errorf("got %v, want only the wrong password to fail", err)
+	}
+	errOut := s.errOut.(*bytes.Buffer).String()
+	if strings.Count(errOut, "wrong password") != 1 || strings.Contains(errOut, "does not exist") {
+		t.Errorf("unexpected errors:\n%v", errOut)
+	}
+	if s.cfg.CurrentUsername != "carol" {
+		t.Errorf("logged in as %q, want carol", s.cfg.CurrentUsername)
+	}
+	if out := s.out.(*bytes.Buffer).String(); !strings.Contains(out, "Now logged in as carol") {
+		t.Errorf("login with the password did not succeed:\n%v", out)
+	}
+}
+
+func TestSplitLine(t *testing.T) {
+	tests := map[string]string{
+		`a  b`:              `[a b]`,
+		`'a b' "c d"`:       `[a b c d]`,
+		`a\ b 'c\d' "e\"f"`: `[a b c\d e"f]`,
+		`x'' ""`:            `[x ]`,
+	}
+	for line, want := range tests {
+		got, err := splitLine(line)
+		if err != nil || fmt.Sprint(got) != want {
+			t.Errorf("%q: got %q %v, want %v", line, got, err, want)
+		}
+	}
+	for _, line := range []string{`'a`, `"a`, `a\`} {
+		if _, err := splitLine(line); err == nil {
+			t.Errorf("%q: want an error", line)
+		}
+	}
+}
+
+func TestShellComplete(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"foll", "follow"},
+		{"feed rm https://q", "feed rm https://quiet.example.com/feed "},
+		{"unfollow te", "unfollow https://blog.example.com/rss.xml "},
+		{"feed rename https://quiet.example.com/feed ", `feed rename https://quiet.example.com/feed "Quiet Blog" `},
+		{"f", "f"},
+	}
+	for _, tt := range tests {
+		var listed bytes.Buffer
+		got, pos, ok := newCommands().shellComplete(s, &listed, tt.line, len(tt.line))
+		if !ok || got != tt.want || pos != len(got) {
+			t.Errorf("%q: got %q at %v (%v), want %q", tt.line, got, pos, ok, tt.want)
+		}
+		if tt.line == "f" && !strings.Contains(listed.String(), "following") {
+			t.Errorf("%q: candidates not listed: %q", tt.line, listed.String())
+		}
+	}
+}
@@ -18,14 +18,44 @@ SELECT
     ON users.id = inserted_feed_follow.user_id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.feed_id, users.name AS user_name, feeds.name AS feed_name, feeds.url AS feed_url, folders.name AS folder_name, feed_follows.title
 FROM feed_follows
 INNER JOIN users
 ON feed_follows.user_id = users.id
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
-WHERE feed_follows.user_id = $1;
+LEFT JOIN folders
+ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+ORDER BY COALESCE(feed_follows.title, feeds.name);
 
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: GetFollowedFeedsForUser :many
+SELECT feeds.* FROM feeds
+INNER JOIN feed_follows
+ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.name;
+
+-- name: CountFollowsForFeed :one
+SELECT count(*) FROM feed_follows
+WHERE feed_id = $1;
+
+-- name: MoveFeedFollows :execrows
+UPDATE feed_follows SET feed_id = sqlc.arg(to_feed_id), updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND user_id NOT IN (
+    SELECT user_id FROM feed_follows
+    WHERE feed_id = sqlc.arg(to_feed_id)
+);
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows SET folder_id = $3, updated_at = $4
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: SetFeedFollowTitle :execrows
+UPDATE feed_follows SET title = $3, updated_at = $4
 WHERE user_id = $1 AND feed_id = $2;
\ No newline at end of file
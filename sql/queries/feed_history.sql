@@ -0,0 +1,19 @@
+-- name: CreateFeedHistory :exec
+INSERT INTO feed_history (id, created_at, feed_id, event, old_value, new_value)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: GetFeedHistory :many
+SELECT * FROM feed_history
+WHERE feed_id = $1
+ORDER BY created_at ASC;
+
+-- name: MoveFeedHistory :exec
+UPDATE feed_history SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- name: GetFeedRetention :one
+SELECT * FROM feed_retention
+WHERE feed_id = $1;
+
+-- name: GetFeedRetentions :many
+SELECT * FROM feed_retention;
+
+-- name: SetFeedRetention :exec
+INSERT INTO feed_retention (feed_id, updated_at, keep_days, keep_posts)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (feed_id) DO UPDATE SET updated_at = excluded.updated_at, keep_days = excluded.keep_days, keep_posts = excluded.keep_posts;
+
+-- name: DeleteFeedRetention :exec
+DELETE FROM feed_retention
+WHERE feed_id = $1;
\ No newline at end of file
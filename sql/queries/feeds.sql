@@ -1,12 +1,14 @@
 -- name: PostFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, link, description)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7,
+    $8
 )
 RETURNING *;
 
@@ -24,4 +26,18 @@ WHERE id = $3;
 -- name: GetNextFeedToFetch :one
 SELECT * FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: UpdateFeedName :one
+UPDATE feeds SET name = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: UpdateFeedURL :one
+UPDATE feeds SET url = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
\ No newline at end of file
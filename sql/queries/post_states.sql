@@ -0,0 +1,40 @@
+-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = excluded.read_at;
+
+-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO UPDATE SET starred_at = excluded.starred_at;
+
+-- name: GetPostEntries :many
+SELECT posts.id, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id,
+    COALESCE(feed_follows.title, feeds.name) AS feed_name, post_states.read_at, post_states.starred_at
+FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND post_states.hidden_at IS NULL
+ORDER BY posts.published_at DESC
+LIMIT $2;
+
+-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO UPDATE SET hidden_at = excluded.hidden_at;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id, tag) DO NOTHING;
+
+-- name: GetPostTags :many
+SELECT tag FROM post_tags
+WHERE user_id = $1 AND post_id = $2
+ORDER BY tag;
+
+-- name: GetTagsForUser :many
+SELECT DISTINCT tag FROM post_tags
+WHERE user_id = $1
+ORDER BY tag;
\ No newline at end of file
@@ -1,5 +1,5 @@
 -- name: PostPost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
 VALUES (
     $1,
     $2,
@@ -8,13 +8,104 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
-)RETURNING *;
+    $8,
+    $9,
+    $10
+)
+ON CONFLICT (url) DO NOTHING
+RETURNING *;
 
 -- name: GetPostsForUser :many
+SELECT posts.*, feed_follows.*, COALESCE(feed_follows.title, feeds.name) AS feed_title FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN post_states
+ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(folder_id) IS NULL OR feed_follows.folder_id = sqlc.narg(folder_id))
+AND (post_states.hidden_at IS NOT NULL) = sqlc.arg(hidden)
+AND (sqlc.narg(tag) IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = posts.id AND post_tags.user_id = feed_follows.user_id AND post_tags.tag = sqlc.narg(tag)
+))
+ORDER BY posts.published_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountPostsForFeed :one
+SELECT count(*) FROM posts
+WHERE feed_id = $1;
+
+-- name: MovePosts :execrows
+UPDATE posts SET feed_id = sqlc.arg(to_feed_id), updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: GetPost :one
 SELECT * FROM posts
+WHERE id = $1;
+
+-- name: CountPostsForUser :one
+SELECT count(*) FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1;
+
+-- name: DeleteAllPosts :execrows
+DELETE FROM posts;
+
+-- name: GetPostsToPrune :many
+SELECT * FROM posts
+WHERE posts.feed_id = sqlc.arg(feed_id)
+AND (
+    posts.published_at < sqlc.narg(published_before)
+    OR (
+        SELECT COUNT(*) FROM posts AS newer
+        WHERE newer.feed_id = posts.feed_id AND newer.published_at > posts.published_at
+    ) >= sqlc.narg(keep_posts)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id AND post_states.starred_at IS NOT NULL
+)
+AND NOT (
+    posts.created_at > sqlc.arg(unread_since)
+    AND EXISTS (
+        SELECT 1 FROM feed_follows
+        LEFT JOIN post_states
+        ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+        WHERE feed_follows.feed_id = posts.feed_id AND post_states.read_at IS NULL
+    )
+)
+ORDER BY posts.published_at;
+
+-- name: PrunePosts :execrows
+DELETE FROM posts
+WHERE posts.feed_id = sqlc.arg(feed_id)
+AND (
+    posts.published_at < sqlc.narg(published_before)
+    OR (
+        SELECT COUNT(*) FROM posts AS newer
+        WHERE newer.feed_id = posts.feed_id AND newer.published_at > posts.published_at
+    ) >= sqlc.narg(keep_posts)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id AND post_states.starred_at IS NOT NULL
+)
+AND NOT (
+    posts.created_at > sqlc.arg(unread_since)
+    AND EXISTS (
+        SELECT 1 FROM feed_follows
+        LEFT JOIN post_states
+        ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+        WHERE feed_follows.feed_id = posts.feed_id AND post_states.read_at IS NULL
+    )
+);
+
+-- name: GetPostsOfFollowedFeeds :many
+SELECT posts.* FROM posts
 INNER JOIN feed_follows
 ON posts.feed_id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
\ No newline at end of file
+ORDER BY posts.published_at;
\ No newline at end of file
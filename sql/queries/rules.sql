@@ -0,0 +1,37 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, user_id, feed_id, field, pattern, regex, action, tag)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT rules.*, feeds.url AS feed_url FROM rules
+LEFT JOIN feeds
+ON rules.feed_id = feeds.id
+WHERE rules.user_id = $1
+ORDER BY rules.created_at;
+
+-- name: GetRulesForFeed :many
+SELECT rules.* FROM rules
+INNER JOIN feed_follows
+ON feed_follows.user_id = rules.user_id
+WHERE feed_follows.feed_id = $1
+AND (rules.feed_id IS NULL OR rules.feed_id = $1)
+ORDER BY rules.created_at;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: MoveRules :exec
+UPDATE rules SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
\ No newline at end of file
@@ -1,10 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
@@ -20,4 +22,55 @@ WHERE id = $1;
 SELECT name FROM users;
 
 -- name: ResetUsers :execrows
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: GetAdmins :many
+SELECT name FROM users
+WHERE is_admin
+ORDER BY name;
+
+-- name: SetUserAdmin :exec
+UPDATE users
+SET is_admin = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: UpdateUserName :one
+UPDATE users
+SET name = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUserStats :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS follows,
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = $1) AS feeds_created,
+    (
+        SELECT COUNT(*) FROM posts
+        INNER JOIN feed_follows
+        ON posts.feed_id = feed_follows.feed_id
+        LEFT JOIN post_states
+        ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+        WHERE feed_follows.user_id = $1 AND post_states.read_at IS NULL AND post_states.hidden_at IS NULL
+    ) AS unread,
+    (
+        SELECT COUNT(*) FROM posts
+        INNER JOIN feeds
+        ON posts.feed_id = feeds.id
+        WHERE feeds.user_id = $1
+    ) AS posts_of_feeds_created,
+    (
+        SELECT COUNT(*) FROM feed_follows
+        INNER JOIN feeds
+        ON feed_follows.feed_id = feeds.id
+        WHERE feeds.user_id = $1 AND feed_follows.user_id <> $1
+    ) AS others_follows_of_feeds_created,
+    (SELECT COUNT(*) FROM post_states WHERE post_states.user_id = $1) AS post_states;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN link TEXT,
+ADD COLUMN description TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN link,
+DROP COLUMN description;
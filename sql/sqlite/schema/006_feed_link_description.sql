@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN link TEXT;
+ALTER TABLE feeds ADD COLUMN description TEXT;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN link;
+ALTER TABLE feeds DROP COLUMN description;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE feed_history(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    feed_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    CONSTRAINT fk_feed_id FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_history;
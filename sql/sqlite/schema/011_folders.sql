@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE folders(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT unique_user_folder UNIQUE (user_id, name)
+);
+
+-- Deleting a folder leaves its follows unfiled
+ALTER TABLE feed_follows ADD COLUMN folder_id TEXT REFERENCES folders(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN folder_id;
+
+DROP TABLE folders;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- A title of the user's own for the feed, shown instead of feeds.name when set
+ALTER TABLE feed_follows ADD COLUMN title TEXT;
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN title;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Retention settings of a feed, overriding the keep_days and keep_posts of the config.
+-- A NULL setting falls back to the config, 0 keeps posts regardless of it.
+CREATE TABLE feed_retention(
+    feed_id TEXT PRIMARY KEY,
+    updated_at TIMESTAMP NOT NULL,
+    keep_days INTEGER,
+    keep_posts INTEGER,
+    CONSTRAINT fk_feed_id FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_retention;
@@ -0,0 +1,40 @@
+-- +goose Up
+-- Who wrote a post and the categories it is filed under, one per line, for rules to match
+ALTER TABLE posts ADD COLUMN author TEXT;
+ALTER TABLE posts ADD COLUMN categories TEXT;
+
+ALTER TABLE post_states ADD COLUMN hidden_at TIMESTAMP;
+
+-- A rule with no feed_id applies to every feed its user follows
+CREATE TABLE rules(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL,
+    feed_id TEXT,
+    field TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    regex BOOLEAN NOT NULL DEFAULT FALSE,
+    action TEXT NOT NULL,
+    tag TEXT,
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_feed_id FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE TABLE post_tags(
+    user_id TEXT NOT NULL,
+    post_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (user_id, post_id, tag),
+    CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_post_id FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE post_tags;
+
+DROP TABLE rules;
+
+ALTER TABLE post_states DROP COLUMN hidden_at;
+
+ALTER TABLE posts DROP COLUMN categories;
+ALTER TABLE posts DROP COLUMN author;
@@ -1,17 +1,88 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
 	"github.com/ChipsAhoyEnjoyer/gator/internal/config"
 	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/ChipsAhoyEnjoyer/gator/internal/migrate"
 )
 
 type state struct {
-	cfg *config.Config
-	db  *database.Queries
+	cfg     *config.Config
+	db      database.Querier
+	conn    *sql.DB
+	dialect migrate.Dialect
+	// Handlers read and write through these instead of os.Stdin/os.Stdout/os.Stderr
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+	// lines buffers linesFrom, the in it was made for, for readLine
+	lines     *bufio.Reader
+	linesFrom io.Reader
+	// format listing commands write their results in
+	format outputFormat
 }
 
 func createStateInstance() *state {
 	return &state{
-		cfg: &config.Config{},
+		cfg:    &config.Config{},
+		in:     os.Stdin,
+		out:    os.Stdout,
+		errOut: os.Stderr,
+		format: outputTable,
+	}
+}
+
+// readLine reads a line typed in reply to a prompt, without its line ending. Every prompt
+// reads through the same buffer so that answers piped in together are not lost.
+// It returns io.EOF once input has run out.
+func (s *state) readLine() (string, error) {
+	if s.lines == nil || s.linesFrom != s.in {
+		s.lines = bufio.NewReader(s.in)
+		s.linesFrom = s.in
+	}
+	line, err := s.lines.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// prompt asks question and returns the trimmed reply, or def when the reply is empty or input has run out
+func (s *state) prompt(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(s.out, "%v [%v]: ", question, def)
+	} else {
+		fmt.Fprintf(s.out, "%v: ", question)
+	}
+	line, err := s.readLine()
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("error: could not read reply \n%v", err)
+	}
+	if err == io.EOF {
+		fmt.Fprintln(s.out)
+	}
+	return cmp.Or(strings.TrimSpace(line), def), nil
+}
+
+// confirm asks the user to type 'yes' before going on with what, unless --yes was given
+func (s *state) confirm(cmd command, what string) error {
+	if cmd.has("yes") {
+		return nil
+	}
+	reply, err := s.prompt(fmt.Sprintf("%v Type 'yes' to confirm", what), "")
+	if err != nil {
+		return err
+	}
+	if reply != "yes" {
+		return fmt.Errorf("error: aborted, nothing was changed (pass --yes to confirm without being asked)")
 	}
+	return nil
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	"github.com/ChipsAhoyEnjoyer/gator/internal/migrate"
+	"github.com/ChipsAhoyEnjoyer/gator/internal/sqlite"
+)
+
+const sqliteScheme = "sqlite:"
+
+// openStorage connects to the database named by dbURL.
+// 'sqlite:<path>' (or 'sqlite://<path>') selects a SQLite file, anything else is handed to Postgres.
+func openStorage(s *state, dbURL string) error {
+	if strings.HasPrefix(dbURL, sqliteScheme) {
+		path, err := sqlitePath(dbURL)
+		if err != nil {
+			return err
+		}
+		db, err := sqlite.Open(path)
+		if err != nil {
+			return fmt.Errorf("error: cannot open sqlite database '%v' \n%v", path, err)
+		}
+		s.conn = db
+		s.db = sqlite.New(db)
+		s.dialect = migrate.SQLite
+		return nil
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return err
+	}
+	s.conn = db
+	s.db = database.New(db)
+	s.dialect = migrate.Postgres
+	return nil
+}
+
+// txStorage is storage undoing changes itself, like the in-memory storage tests use
+type txStorage interface {
+	InTx(ctx context.Context, fn func() error) error
+}
+
+// inTx runs fn with a copy of s whose queries all commit together, or not at all when fn returns an error
+func inTx(s *state, fn func(tx *state) error) error {
+	if storage, ok := s.db.(txStorage); ok {
+		return storage.InTx(context.Background(), func() error {
+			return fn(s)
+		})
+	}
+	tx, err := s.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("error: could not start a transaction \n%v", err)
+	}
+	defer tx.Rollback()
+	txState := *s
+	switch db := s.db.(type) {
+	case *sqlite.Queries:
+		txState.db = db.WithTx(tx)
+	case *database.Queries:
+		txState.db = db.WithTx(tx)
+	default:
+		return fmt.Errorf("error: storage %T does not support transactions", s.db)
+	}
+	err = fn(&txState)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error: could not commit transaction \n%v", err)
+	}
+	return nil
+}
+
+func sqlitePath(dbURL string) (string, error) {
+	path := strings.TrimPrefix(dbURL, sqliteScheme)
+	path = strings.TrimPrefix(path, "//")
+	if path == "" {
+		return "", fmt.Errorf("error: db_url '%v' names no database file, use 'sqlite:<path>'", dbURL)
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("error: cannot retreive home directory\n%v", err)
+		}
+		path = filepath.Join(homeDir, path[1:])
+	}
+	return path, nil
+}
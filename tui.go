@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+)
+
+const (
+	// tuiPostLimit caps how many of the newest posts the tui loads
+	tuiPostLimit = 1000
+	// tuiPollInterval is how often the tui checks whether agg saved new posts
+	tuiPollInterval = 5 * time.Second
+	tuiSidebarWidth = 28
+)
+
+type tuiPane int
+
+const (
+	paneSidebar tuiPane = iota
+	panePosts
+	paneReader
+)
+
+// sidebarItem is a view of the loaded posts, e.g. a feed or the starred posts
+type sidebarItem struct {
+	label string
+	show  func(database.GetPostEntriesRow) bool
+}
+
+type tuiModel struct {
+	s    *state
+	user database.User
+	open func(url string) error
+
+	entries []database.GetPostEntriesRow
+	sidebar []sidebarItem
+	// visible holds the indexes in entries of the posts under the selected sidebar item
+	visible []int
+	sideIdx int
+	postIdx int
+	// reading is the index in entries of the post in the reader pane, -1 for none
+	reading int
+	scroll  int
+	focus   tuiPane
+
+	width  int
+	height int
+	// loaded is the number of posts the user had when entries were loaded
+	loaded   int64
+	newPosts int64
+	status   string
+}
+
+// Messages sent to the tui by its commands
+type (
+	entriesMsg struct {
+		entries []database.GetPostEntriesRow
+		follows []database.GetFeedFollowsForUserRow
+		count   int64
+		err     error
+	}
+	pollMsg struct {
+		count int64
+		err   error
+	}
+	tickMsg struct{}
+)
+
+func newTUIModel(s *state, user database.User) *tuiModel {
+	return &tuiModel{
+		s:       s,
+		user:    user,
+		open:    openInBrowser,
+		reading: -1,
+		status:  "Loading posts...",
+	}
+}
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+	p := tea.NewProgram(newTUIModel(s, user), tea.WithAltScreen(), tea.WithInput(s.in), tea.WithOutput(s.out))
+	_, err := p.Run()
+	if err != nil {
+		return fmt.Errorf("error: could not run the tui \n%v", err)
+	}
+	return nil
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return tea.Batch(m.load, m.tick())
+}
+
+func (m *tuiModel) load() tea.Msg {
+	ctx := context.Background()
+	entries, err := m.s.db.GetPostEntries(ctx, database.GetPostEntriesParams{UserID: m.user.ID, Limit: tuiPostLimit})
+	if err != nil {
+		return entriesMsg{err: err}
+	}
+	follows, err := m.s.db.GetFeedFollowsForUser(ctx, m.user.ID)
+	if err != nil {
+		return entriesMsg{err: err}
+	}
+	count, err := m.s.db.CountPostsForUser(ctx, m.user.ID)
+	return entriesMsg{entries: entries, follows: follows, count: count, err: err}
+}
+
+func (m *tuiModel) tick() tea.Cmd {
+	return tea.Tick(tuiPollInterval, func(time.Time) tea.Msg { return tickMsg{} })
+}
+
+func (m *tuiModel) poll() tea.Msg {
+	count, err := m.s.db.CountPostsForUser(context.Background(), m.user.ID)
+	return pollMsg{count: count, err: err}
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case entriesMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Could not load posts: %v", msg.err)
+			return m, nil
+		}
+		m.setEntries(msg.entries, msg.follows)
+		m.loaded, m.newPosts = msg.count, 0
+		m.status = fmt.Sprintf("Loaded %v post(s)", len(msg.entries))
+	case tickMsg:
+		return m, tea.Batch(m.poll, m.tick())
+	case pollMsg:
+		if msg.err == nil && msg.count > m.loaded {
+			m.newPosts = msg.count - m.loaded
+		}
+	case tea.KeyMsg:
+		return m.key(msg)
+	}
+	return m, nil
+}
+
+func (m *tuiModel) key(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "tab", "l", "right":
+		m.focus = min(m.focus+1, paneReader)
+	case "shift+tab", "h", "left":
+		m.focus = max(m.focus-1, paneSidebar)
+	case "j", "down":
+		m.move(1)
+	case "k", "up":
+		m.move(-1)
+	case "pgdown", " ":
+		m.move(m.paneHeight() - 1)
+	case "pgup":
+		m.move(1 - m.paneHeight())
+	case "g", "home":
+		m.move(-len(m.entries) - m.scroll)
+	case "G", "end":
+		m.move(len(m.entries) + len(m.readerLines()))
+	case "enter":
+		switch m.focus {
+		case paneSidebar:
+			m.focus = panePosts
+		case panePosts:
+			m.read()
+		}
+	case "m":
+		if i, ok := m.selected(); ok {
+			m.setRead(i, !m.entries[i].ReadAt.Valid)
+		}
+	case "s":
+		if i, ok := m.selected(); ok {
+			m.toggleStar(i)
+		}
+	case "o":
+		if i, ok := m.selected(); ok {
+			if err := m.open(m.entries[i].Url); err != nil {
+				m.status = fmt.Sprintf("Could not open %v: %v", m.entries[i].Url, err)
+			} else {
+				m.status = fmt.Sprintf("Opened %v", m.entries[i].Url)
+				m.setRead(i, true)
+			}
+		}
+	case "r":
+		m.status = "Loading posts..."
+		return m, m.load
+	}
+	return m, nil
+}
+
+// setEntries replaces the loaded posts, keeping the sidebar selection
+func (m *tuiModel) setEntries(entries []database.GetPostEntriesRow, follows []database.GetFeedFollowsForUserRow) {
+	m.entries = entries
+	m.sidebar = []sidebarItem{
+		{label: "All", show: func(database.GetPostEntriesRow) bool { return true }},
+		{label: "Unread", show: func(e database.GetPostEntriesRow) bool { return !e.ReadAt.Valid }},
+		{label: "Starred", show: func(e database.GetPostEntriesRow) bool { return e.StarredAt.Valid }},
+	}
+	// Feeds outside any folder come first, then each folder followed by its feeds
+	sort.Slice(follows, func(i, j int) bool {
+		if follows[i].FolderName != follows[j].FolderName {
+			return !follows[i].FolderName.Valid || follows[i].FolderName.String < follows[j].FolderName.String
+		}
+		return followTitle(follows[i]) < followTitle(follows[j])
+	})
+	for i, f := range follows {
+		if f.FolderName.Valid && (i == 0 || follows[i-1].FolderName != f.FolderName) {
+			folder := f.FolderName.String
+			feedIDs := make(map[uuid.UUID]bool)
+			for _, g := range follows[i:] {
+				if g.FolderName.String == folder {
+					feedIDs[g.FeedID] = true
+				}
+			}
+			m.sidebar = append(m.sidebar, sidebarItem{
+				label: folder + "/",
+				show:  func(e database.GetPostEntriesRow) bool { return feedIDs[e.FeedID] },
+			})
+		}
+		label := followTitle(f)
+		if f.FolderName.Valid {
+			label = "  " + label
+		}
+		feedID := f.FeedID
+		m.sidebar = append(m.sidebar, sidebarItem{
+			label: label,
+			show:  func(e database.GetPostEntriesRow) bool { return e.FeedID == feedID },
+		})
+	}
+	m.sideIdx = min(m.sideIdx, len(m.sidebar)-1)
+	m.reading = -1
+	m.scroll = 0
+	m.filter()
+}
+
+// filter recomputes the posts shown for the selected sidebar item
+func (m *tuiModel) filter() {
+	m.visible = m.visible[:0]
+	for i, e := range m.entries {
+		if m.sidebar[m.sideIdx].show(e) {
+			m.visible = append(m.visible, i)
+		}
+	}
+	m.postIdx = max(0, min(m.postIdx, len(m.visible)-1))
+}
+
+func (m *tuiModel) move(delta int) {
+	switch m.focus {
+	case paneSidebar:
+		idx := max(0, min(m.sideIdx+delta, len(m.sidebar)-1))
+		if idx != m.sideIdx {
+			m.sideIdx = idx
+			m.postIdx = 0
+			m.filter()
+		}
+	case panePosts:
+		m.postIdx = max(0, min(m.postIdx+delta, len(m.visible)-1))
+	case paneReader:
+		m.scroll = max(0, min(m.scroll+delta, len(m.readerLines())-m.paneHeight()))
+	}
+}
+
+// selected returns the index in entries of the post the keys act on
+func (m *tuiModel) selected() (int, bool) {
+	if m.focus == paneReader && m.reading >= 0 {
+		return m.reading, true
+	}
+	if len(m.visible) == 0 {
+		return 0, false
+	}
+	return m.visible[m.postIdx], true
+}
+
+func (m *tuiModel) read() {
+	i, ok := m.selected()
+	if !ok {
+		return
+	}
+	m.reading = i
+	m.scroll = 0
+	m.focus = paneReader
+	if !m.entries[i].ReadAt.Valid {
+		m.setRead(i, true)
+	}
+}
+
+func (m *tuiModel) setRead(i int, read bool) {
+	err := setPostRead(m.s, m.user, m.entries[i].ID, read)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.entries[i].ReadAt.Valid = read
+	m.entries[i].ReadAt.Time = time.Now()
+}
+
+func (m *tuiModel) toggleStar(i int) {
+	starred := !m.entries[i].StarredAt.Valid
+	err := setPostStarred(m.s, m.user, m.entries[i].ID, starred)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.entries[i].StarredAt.Valid = starred
+	m.entries[i].StarredAt.Time = time.Now()
+}
+
+var (
+	tuiPaneStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
+	tuiFocusStyle   = tuiPaneStyle.BorderForeground(lipgloss.Color("42"))
+	tuiCursorStyle  = lipgloss.NewStyle().Reverse(true)
+	tuiTitleStyle   = lipgloss.NewStyle().Bold(true)
+	tuiDimStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	tuiNewPostStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+	tuiLiveStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+)
+
+// paneHeight is the number of lines inside a pane's border
+func (m *tuiModel) paneHeight() int {
+	return max(1, m.height-3)
+}
+
+func (m *tuiModel) paneWidths() (int, int, int) {
+	rest := max(20, m.width-tuiSidebarWidth-6)
+	posts := rest * 2 / 5
+	return tuiSidebarWidth, posts, rest - posts
+}
+
+func (m *tuiModel) View() string {
+	if m.width == 0 {
+		return m.status
+	}
+	sideW, postsW, readerW := m.paneWidths()
+	h := m.paneHeight()
+
+	side := make([]string, len(m.sidebar))
+	for i, item := range m.sidebar {
+		unread := 0
+		for _, e := range m.entries {
+			if item.show(e) && !e.ReadAt.Valid {
+				unread++
+			}
+		}
+		line := item.label
+		if unread > 0 {
+			line = fmt.Sprintf("%v (%v)", item.label, unread)
+		}
+		side[i] = m.line(line, sideW, i == m.sideIdx, m.focus == paneSidebar)
+	}
+
+	posts := make([]string, len(m.visible))
+	for n, i := range m.visible {
+		e := m.entries[i]
+		marker := "  "
+		if e.StarredAt.Valid {
+			marker = "★ "
+		} else if !e.ReadAt.Valid {
+			marker = "● "
+		}
+		posts[n] = m.line(marker+e.Title, postsW, n == m.postIdx, m.focus == panePosts)
+	}
+	if len(posts) == 0 {
+		posts = []string{tuiDimStyle.Render("No posts")}
+	}
+
+	reader := m.readerLines()
+	start := min(m.scroll, max(0, len(reader)-h))
+	reader = reader[start:min(len(reader), start+h)]
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			m.pane(paneSidebar, sideW, window(side, m.sideIdx, h)),
+			m.pane(panePosts, postsW, window(posts, m.postIdx, h)),
+			m.pane(paneReader, readerW, reader),
+		),
+		m.statusLine(),
+	)
+}
+
+func (m *tuiModel) pane(p tuiPane, width int, lines []string) string {
+	style := tuiPaneStyle
+	if m.focus == p {
+		style = tuiFocusStyle
+	}
+	return style.Width(width).Height(m.paneHeight()).Render(strings.Join(lines, "\n"))
+}
+
+// line renders a list entry cut to width, highlighted when it is the cursor
+func (m *tuiModel) line(text string, width int, cursor, focused bool) string {
+	text = lipgloss.NewStyle().MaxWidth(width).Render(strings.ReplaceAll(text, "\n", " "))
+	if cursor && focused {
+		return tuiCursorStyle.Width(width).Render(text)
+	}
+	if cursor {
+		return tuiTitleStyle.Render(text)
+	}
+	return text
+}
+
+// window returns the lines of a list that fit in height, keeping the cursor in view
+func window(lines []string, cursor, height int) []string {
+	start := max(0, min(cursor-height/2, len(lines)-height))
+	return lines[start:min(len(lines), start+height)]
+}
+
+func (m *tuiModel) readerLines() []string {
+	if m.reading < 0 {
+		return []string{tuiDimStyle.Render("Select a post and press enter to read it")}
+	}
+	_, _, width := m.paneWidths()
+	e := m.entries[m.reading]
+	wrap := lipgloss.NewStyle().Width(width)
+	text := strings.Join([]string{
+		tuiTitleStyle.Render(wrap.Render(e.Title)),
+		tuiDimStyle.Render(wrap.Render(fmt.Sprintf("%v · %v", e.FeedName, e.PublishedAt.Local().Format("Mon, 02 Jan 2006 15:04")))),
+		tuiDimStyle.Render(wrap.Render(e.Url)),
+		"",
+		wrap.Render(htmlToText(e.Description.String)),
+	}, "\n")
+	return strings.Split(text, "\n")
+}
+
+func (m *tuiModel) statusLine() string {
+	left := fmt.Sprintf(" %v · %v", m.user.Name, m.status)
+	live := tuiLiveStyle.Render("● live")
+	if m.newPosts > 0 {
+		live = tuiNewPostStyle.Render(fmt.Sprintf("● %v new post(s), press r to load", m.newPosts))
+	}
+	keys := tuiDimStyle.Render("tab/h/l pane · j/k move · enter read · m read · s star · o open · q quit ")
+	gap := max(1, m.width-lipgloss.Width(left)-lipgloss.Width(live)-lipgloss.Width(keys)-2)
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(left + " " + live + strings.Repeat(" ", gap) + keys)
+}
+
+// htmlToText reduces a post description to plain text, one paragraph per block element
+func htmlToText(description string) string {
+	doc, err := html.Parse(strings.NewReader(description))
+	if err != nil {
+		return description
+	}
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(strings.Join(strings.Fields(n.Data), " "))
+			if strings.HasSuffix(n.Data, " ") || strings.HasSuffix(n.Data, "\n") {
+				b.WriteString(" ")
+			}
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style":
+				return
+			case "br":
+				b.WriteString("\n")
+			case "li":
+				b.WriteString("\n• ")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "pre", "ul", "ol", "table":
+				b.WriteString("\n\n")
+			}
+		}
+	}
+	walk(doc)
+	paragraphs := strings.Split(b.String(), "\n")
+	for i, p := range paragraphs {
+		paragraphs[i] = strings.TrimSpace(p)
+	}
+	text := strings.Join(paragraphs, "\n")
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(text)
+}
+
+// openInBrowser opens url with the desktop's default handler
+func openInBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
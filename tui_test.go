@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+// press sends keys to a tui model, one key per rune or a named key like "enter"
+func press(m *tuiModel, keys ...string) {
+	for _, k := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		}
+		m.Update(msg)
+	}
+}
+
+func TestTUI(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	user, _ := s.db.GetUser(context.Background(), "alice")
+	m := newTUIModel(s, user)
+	opened := ""
+	m.open = func(url string) error {
+		opened = url
+		return nil
+	}
+	m.Update(tea.WindowSizeMsg{Width: 140, Height: 30})
+	m.Update(m.load())
+
+	view := m.View()
+	for _, want := range []string{"All (3)", "Unread (3)", "Starred", "Quiet Blog", "Test Blog (3)", "● Third", "● live"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view is missing %q:\n%v", want, view)
+		}
+	}
+
+	// Read the second post: it shows in the reader and is marked read
+	press(m, "enter", "j", "enter")
+	if m.focus != paneReader || m.entries[m.reading].Title != "Second" {
+		t.Fatalf("reader shows %v with focus %v, want Second", m.reading, m.focus)
+	}
+	if view := m.View(); !strings.Contains(view, "Post number 2") || !strings.Contains(view, "Unread (2)") {
+		t.Errorf("view after reading Second:\n%v", view)
+	}
+	press(m, "s", "o")
+	if opened != "https://blog.example.com/2" {
+		t.Errorf("opened %q, want the post's link", opened)
+	}
+
+	// The Starred view only holds the starred post
+	press(m, "h", "h", "j", "j", "enter")
+	if len(m.visible) != 1 || m.entries[m.visible[0]].Title != "Second" {
+		t.Errorf("starred view holds %v", m.visible)
+	}
+	press(m, "m")
+
+	entries, err := s.db.GetPostEntries(context.Background(), database.GetPostEntriesParams{UserID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.ReadAt.Valid || e.StarredAt.Valid != (e.Title == "Second") {
+			t.Errorf("%v: read %v starred %v", e.Title, e.ReadAt.Valid, e.StarredAt.Valid)
+		}
+	}
+
+	// A post saved by agg shows up as a refresh indicator until the posts are reloaded
+	_, err = s.db.PostPost(context.Background(), database.PostPostParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Title:       "Fourth",
+		Url:         "https://blog.example.com/4",
+		PublishedAt: time.Now(),
+		FeedID:      entries[0].FeedID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Update(m.poll())
+	if !strings.Contains(m.View(), "1 new post(s)") {
+		t.Errorf("no refresh indicator:\n%v", m.View())
+	}
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m.Update(cmd())
+	if m.newPosts != 0 || len(m.entries) != 4 {
+		t.Errorf("after reload: %v new, %v loaded", m.newPosts, len(m.entries))
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	got := htmlToText("<p>Hello <b>world</b>,</p><ul><li>one</li><li>two</li></ul><script>x()</script>bye<br>now")
+	want := "Hello world,\n\n• one\n• two\n\nbye\nnow"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
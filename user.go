@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ChipsAhoyEnjoyer/gator/internal/database"
+)
+
+// targetUser returns the user named by the first of args, or user when args holds no name.
+// Only admins may act on someone else.
+func targetUser(s *state, user database.User, args []string) (database.User, error) {
+	if len(args) == 0 || args[0] == user.Name {
+		return user, nil
+	}
+	if !hasAdminRights(user) {
+		return database.User{}, fmt.Errorf("error: only admins logged in with a password can change other users")
+	}
+	target, err := s.db.GetUser(context.Background(), args[0])
+	if err != nil {
+		return database.User{}, fmt.Errorf("error: user '%v' not registered", args[0])
+	}
+	return target, nil
+}
+
+// deleteUser deletes target along with the feeds they added, once confirmed, and logs
+// them out if they are the current user. The last admin cannot be deleted.
+func deleteUser(s *state, cmd command, target database.User) error {
+	if target.IsAdmin {
+		admins, err := s.db.GetAdmins(context.Background())
+		if err != nil {
+			return fmt.Errorf("error: could not retreive admins from db \n%v", err)
+		}
+		if len(admins) == 1 {
+			return fmt.Errorf("error: %v is the only admin, make someone else an admin first", target.Name)
+		}
+	}
+	stats, err := s.db.GetUserStats(context.Background(), target.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not retreive details of %v \n%v", target.Name, err)
+	}
+	err = s.confirm(cmd, fmt.Sprintf(
+		"This deletes %v with their %v follow(s) and %v read or starred mark(s), "+
+			"and the %v feed(s) they added with %v post(s) and %v follow(s) by other users.",
+		target.Name,
+		stats.Follows,
+		stats.PostStates,
+		stats.FeedsCreated,
+		stats.PostsOfFeedsCreated,
+		stats.OthersFollowsOfFeedsCreated,
+	))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.DeleteUser(context.Background(), target.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not delete user %v \n%v", target.Name, err)
+	}
+	if target.Name == s.cfg.CurrentUsername {
+		err = s.cfg.SetUser("", "")
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(s.out, "Deleted user %v and %v feed(s)\n", target.Name, stats.FeedsCreated)
+	return nil
+}
+
+func handlerUserRename(s *state, cmd command, user database.User) error {
+	target, err := targetUser(s, user, cmd.args[:len(cmd.args)-1])
+	if err != nil {
+		return err
+	}
+	name := cmd.args[len(cmd.args)-1]
+	if name == "" {
+		return usageErrorf("usage: gator user rename [<username>] <new name>")
+	}
+	if userExists(s, name) {
+		return fmt.Errorf("error: user '%v' exists", name)
+	}
+	renamed, err := s.db.UpdateUserName(
+		context.Background(),
+		database.UpdateUserNameParams{
+			ID:        target.ID,
+			Name:      name,
+			UpdatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error: could not rename %v \n%v", target.Name, err)
+	}
+	if target.Name == s.cfg.CurrentUsername {
+		err = s.cfg.SetUser(renamed.Name, s.cfg.SessionToken)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(s.out, "Renamed user %v to %v\n", target.Name, renamed.Name)
+	return nil
+}
+
+func handlerUserDelete(s *state, cmd command, user database.User) error {
+	target, err := targetUser(s, user, cmd.args)
+	if err != nil {
+		return err
+	}
+	return deleteUser(s, cmd, target)
+}
+
+// accountAge describes how long ago an account was created
+func accountAge(createdAt, now time.Time) string {
+	days := int(now.Sub(createdAt).Hours() / 24)
+	switch {
+	case days < 1:
+		return "less than a day"
+	case days < 365:
+		return fmt.Sprintf("%v day(s)", days)
+	}
+	return fmt.Sprintf("%v year(s) and %v day(s)", days/365, days%365)
+}
+
+func handlerWhoami(s *state, cmd command, user database.User) error {
+	stats, err := s.db.GetUserStats(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error: could not retreive details of %v \n%v", user.Name, err)
+	}
+	record := whoamiRecord{
+		Name:         user.Name,
+		Admin:        user.IsAdmin,
+		Password:     user.PasswordHash.Valid,
+		CreatedAt:    user.CreatedAt,
+		Follows:      stats.Follows,
+		FeedsCreated: stats.FeedsCreated,
+		Unread:       stats.Unread,
+	}
+	if s.format != outputTable {
+		return writeRecords(s, []whoamiRecord{record})
+	}
+	role, password := "user", "none"
+	if user.IsAdmin {
+		role = "admin"
+	}
+	if user.PasswordHash.Valid {
+		password = "set"
+	}
+	fmt.Fprintln(s.out, "=============================WHOAMI=============================")
+	fmt.Fprintf(s.out, "Name:          %v (%v)\n", user.Name, role)
+	fmt.Fprintf(s.out, "Member for:    %v, since %v\n", accountAge(user.CreatedAt, time.Now()), user.CreatedAt.Format(time.DateOnly))
+	fmt.Fprintf(s.out, "Password:      %v\n", password)
+	fmt.Fprintf(s.out, "Following:     %v feed(s)\n", stats.Follows)
+	fmt.Fprintf(s.out, "Feeds added:   %v\n", stats.FeedsCreated)
+	fmt.Fprintf(s.out, "Unread posts:  %v\n", stats.Unread)
+	fmt.Fprintln(s.out)
+	fmt.Fprintln(s.out, "================================================================")
+	return nil
+}
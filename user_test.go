@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserAccounts(t *testing.T) {
+	s := newTestState(t)
+	seedFixture(t, s)
+	ctx := context.Background()
+	mustRun(t, s, "post", "read", "00000000-0000-0000-0000-000000000001")
+	s.format = outputJSON
+	s.out = &bytes.Buffer{}
+	mustRun(t, s, "whoami")
+	assertGolden(t, "whoami_json", s.out.(*bytes.Buffer).Bytes())
+	s.format = outputTable
+
+	mustRun(t, s, "user", "rename", "ally")
+	if s.cfg.CurrentUsername != "ally" || userExists(s, "alice") {
+		t.Errorf("rename not applied, current user is %v", s.cfg.CurrentUsername)
+	}
+	if err := run(t, s, "user", "rename", "bob"); err == nil || !strings.Contains(err.Error(), "exists") {
+		t.Errorf("got %v, want a taken name to be refused", err)
+	}
+	if err := run(t, s, "user", "delete", "--yes"); err == nil || !strings.Contains(err.Error(), "only admin") {
+		t.Errorf("got %v, want the only admin to be kept", err)
+	}
+
+	// bob follows one of ally's feeds, so deleting ally takes bob's follow with it
+	mustRun(t, s, "login", "bob")
+	mustRun(t, s, "follow", "https://blog.example.com/rss.xml")
+	if err := run(t, s, "user", "rename", "ally", "al"); err == nil || !strings.Contains(err.Error(), "only admins") {
+		t.Errorf("got %v, want renaming others to need an admin", err)
+	}
+	mustRun(t, s, "login", "ally")
+	if err := run(t, s, "user", "rename", "bob", "robert"); err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("got %v, want an admin without a password to be refused", err)
+	}
+	loginWithPassword(t, s, "ally")
+	mustRun(t, s, "user", "rename", "bob", "robert")
+	mustRun(t, s, "admin", "grant", "robert")
+
+	s.in = strings.NewReader("no\n")
+	s.out = &bytes.Buffer{}
+	if err := run(t, s, "user", "delete"); err == nil || !strings.Contains(err.Error(), "aborted") {
+		t.Errorf("got %v, want the delete to be aborted", err)
+	}
+	want := "This deletes ally with their 2 follow(s) and 1 read or starred mark(s), " +
+		"and the 2 feed(s) they added with 3 post(s) and 1 follow(s) by other users."
+	if !strings.Contains(s.out.(*bytes.Buffer).String(), want) {
+		t.Errorf("got %q, want the summary %q", s.out.(*bytes.Buffer).String(), want)
+	}
+	s.in = strings.NewReader("yes\n")
+	mustRun(t, s, "user", "delete")
+	if users, _ := s.db.GetUsers(ctx); fmt.Sprint(users) != "[robert]" || s.cfg.CurrentUsername != "" {
+		t.Errorf("got users %v and current user %q", users, s.cfg.CurrentUsername)
+	}
+	if feeds, _ := s.db.GetFeeds(ctx); len(feeds) != 0 {
+		t.Errorf("feeds of the deleted user remain: %v", feeds)
+	}
+}
+
+func TestAccountAge(t *testing.T) {
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[time.Duration]string{
+		time.Hour:            "less than a day",
+		50 * time.Hour:       "2 day(s)",
+		400 * 24 * time.Hour: "1 year(s) and 35 day(s)",
+	}
+	for age, want := range tests {
+		if got := accountAge(now.Add(-age), now); got != want {
+			t.Errorf("%v: got %v, want %v", age, got, want)
+		}
+	}
+}